@@ -0,0 +1,232 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/typescript/typescript"
+	"gopkg.in/yaml.v3"
+)
+
+// globList is a list of globs that can also be written as a single string
+type globList []string
+
+// UnmarshalYAML accepts a single glob as well as a list
+func (g *globList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*g = globList{value.Value}
+		return nil
+	}
+
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	*g = list
+	return nil
+}
+
+// fileImport is a module a file depends on through an import or export
+// statement, a require call or a dynamic import
+type fileImport struct {
+	source  string
+	line    uint32
+	dynamic bool // require() or import() rather than a statement
+}
+
+// validateBoundary checks the globs of an import-boundary rule
+func (r *Rule) validateBoundary() error {
+	if len(r.Allow) == 0 && len(r.Deny) == 0 {
+		return fmt.Errorf("import-boundary rule needs allow or deny")
+	}
+
+	for _, list := range []globList{r.From, r.Allow, r.Deny} {
+		for _, pattern := range list {
+			if !doublestar.ValidatePattern(pattern) {
+				return fmt.Errorf("invalid glob %q", pattern)
+			}
+		}
+	}
+
+	// Globs in a config file are relative to it; other rules use the working directory
+	if r.baseDir == "" {
+		dir, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		r.baseDir = dir
+	}
+
+	return nil
+}
+
+// checkImports reports every import of a file that crosses the boundary.
+// With deny, imports matching deny fail unless they also match allow; with
+// only allow, every import must match allow.
+func (r *Rule) checkImports(rootNode *sitter.Node, content []byte, filename string) []Finding {
+	from := "this file"
+	if len(r.From) > 0 {
+		if from = r.matchPath(r.From, filename); from == "" {
+			return nil
+		}
+	}
+
+	var findings []Finding
+	for _, imp := range findFileImports(rootNode, content) {
+		resolved, _ := resolveModule(filename, imp.source)
+		allowed := r.matchImport(r.Allow, imp.source, resolved) != ""
+
+		var reason string
+		if len(r.Deny) > 0 {
+			denied := r.matchImport(r.Deny, imp.source, resolved)
+			if denied == "" || allowed {
+				continue
+			}
+			reason = fmt.Sprintf("%s may not import %s", from, denied)
+		} else {
+			if allowed {
+				continue
+			}
+			reason = fmt.Sprintf("%s may only import %s", from, strings.Join(r.Allow, ", "))
+		}
+
+		logger.Debug("import crosses boundary", "file", filename, "line", imp.line, "source", imp.source, "resolved", resolved, "reason", reason)
+
+		findings = append(findings, Finding{
+			Line:    imp.line,
+			Message: fmt.Sprintf("Import of %q is not allowed: %s", imp.source, reason),
+		})
+	}
+
+	return findings
+}
+
+// matchImport returns the first glob matching the resolved path of an import
+// or, for package imports, its specifier
+func (r *Rule) matchImport(globs globList, source string, resolved string) string {
+	if resolved != "" {
+		if pattern := r.matchPath(globs, resolved); pattern != "" {
+			return pattern
+		}
+	}
+
+	for _, pattern := range globs {
+		if globMatches(pattern, source) {
+			return pattern
+		}
+	}
+	return ""
+}
+
+// matchPath returns the first glob matching a file, relative to the rule's base directory
+func (r *Rule) matchPath(globs globList, path string) string {
+	relative, err := filepath.Rel(r.baseDir, path)
+	if err != nil || strings.HasPrefix(relative, "..") {
+		return ""
+	}
+	relative = filepath.ToSlash(relative)
+
+	for _, pattern := range globs {
+		if globMatches(pattern, relative) {
+			return pattern
+		}
+	}
+	return ""
+}
+
+// globMatches reports whether a glob matches a path or one of its parent
+// directories, so that packages/db matches everything under it
+func globMatches(pattern string, path string) bool {
+	pattern = strings.TrimPrefix(pattern, "./")
+	if ok, _ := doublestar.Match(pattern, path); ok {
+		return true
+	}
+	ok, _ := doublestar.Match(strings.TrimSuffix(pattern, "/")+"/**", path)
+	return ok
+}
+
+// findFileImports returns the modules a file imports, in source order
+func findFileImports(rootNode *sitter.Node, content []byte) []fileImport {
+	var imports []fileImport
+
+	var walk func(node *sitter.Node)
+	walk = func(node *sitter.Node) {
+		for i := 0; i < int(node.NamedChildCount()); i++ {
+			child := node.NamedChild(i)
+			line := child.StartPoint().Row + 1
+
+			switch child.Type() {
+			case "import_statement", "export_statement":
+				if source := child.ChildByFieldName("source"); source != nil {
+					imports = append(imports, fileImport{source: stringLiteralValue(source, content), line: line})
+				}
+			case "call_expression":
+				// require('module') and import('module')
+				function := child.ChildByFieldName("function")
+				arguments := child.ChildByFieldName("arguments")
+				if function != nil && arguments != nil && arguments.NamedChildCount() > 0 &&
+					(function.Type() == "import" || (function.Type() == "identifier" && nodeText(function, content) == "require")) {
+					if argument := arguments.NamedChild(0); argument.Type() == "string" {
+						imports = append(imports, fileImport{source: stringLiteralValue(argument, content), line: line, dynamic: true})
+					}
+				}
+			}
+
+			walk(child)
+		}
+	}
+	walk(rootNode)
+
+	return imports
+}
+
+// hasBoundaryRule reports whether any rule checks import boundaries
+func hasBoundaryRule(rules []*Rule) bool {
+	for _, rule := range rules {
+		if rule.Type == ruleTypeBoundary {
+			return true
+		}
+	}
+	return false
+}
+
+// importResolutionFingerprint hashes the file every import of every file
+// resolves to. Boundary findings depend on which files exist, not only on the
+// content of the file being checked, so adding or removing an imported file
+// changes the fingerprint.
+func importResolutionFingerprint(files []string) ([]byte, error) {
+	var paths []string
+	for _, file := range files {
+		absPath, err := filepath.Abs(file)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, absPath)
+	}
+	sort.Strings(paths)
+
+	parser := sitter.NewParser()
+	parser.SetLanguage(typescript.GetLanguage())
+
+	hash := sha256.New()
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		rootNode := parser.Parse(nil, content).RootNode()
+
+		fmt.Fprintf(hash, "file %s\n", path)
+		for _, imp := range findFileImports(rootNode, content) {
+			resolved, _ := resolveModule(path, imp.source)
+			fmt.Fprintf(hash, "import %q %q\n", imp.source, resolved)
+		}
+	}
+	return hash.Sum(nil), nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestImportBoundaryRule(t *testing.T) {
+	tempDir := t.TempDir()
+	writeFiles(t, tempDir, map[string]string{
+		"tsconfig.json": `{"compilerOptions": {"baseUrl": ".", "paths": {"@infra/*": ["src/infra/*"]}}}`,
+		".ts-analyzer.yaml": `rules:
+  - type: import-boundary
+    from: src/domain/**
+    deny: src/infra
+    allow: src/infra/types/**
+  - type: import-boundary
+    from: [packages/ui]
+    deny: "@acme/db"
+  - type: import-boundary
+    from: src/domain/value.ts
+    allow: [src/domain/**]
+`,
+		"src/domain/user.ts": `import { db } from '../infra/db';
+import type { User } from '../infra/types/user';
+import { Value } from './value';
+import { cache } from '@infra/cache';
+export { query } from '../infra/db';
+
+export function load() {
+    const legacy = require('../infra/legacy');
+    return import('../infra/db');
+}
+`,
+		"src/domain/value.ts":       "import { z } from 'zod';\nimport { x } from './user';\nexport class Value {}\n",
+		"src/infra/db.ts":           "export const db = {};\n",
+		"src/infra/cache.ts":        "export const cache = {};\n",
+		"src/infra/legacy.ts":       "export const legacy = {};\n",
+		"src/infra/types/user.ts":   "export interface User {}\n",
+		"packages/ui/src/button.ts": "import { client } from '@acme/db/client';\nimport { theme } from '@acme/theme';\n",
+	})
+
+	project, err := loadProject(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to load project: %v", err)
+	}
+	activeProject = project
+	defer func() { activeProject = nil }()
+
+	config, err := loadConfig(filepath.Join(tempDir, ".ts-analyzer.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	expected := map[string][]Finding{
+		"src/domain/user.ts": {
+			{Line: 1, Message: `Import of "../infra/db" is not allowed: src/domain/** may not import src/infra`, Severity: severityError},
+			{Line: 4, Message: `Import of "@infra/cache" is not allowed: src/domain/** may not import src/infra`, Severity: severityError},
+			{Line: 5, Message: `Import of "../infra/db" is not allowed: src/domain/** may not import src/infra`, Severity: severityError},
+			{Line: 8, Message: `Import of "../infra/legacy" is not allowed: src/domain/** may not import src/infra`, Severity: severityError},
+			{Line: 9, Message: `Import of "../infra/db" is not allowed: src/domain/** may not import src/infra`, Severity: severityError},
+		},
+		"src/domain/value.ts": {
+			{Line: 1, Message: `Import of "zod" is not allowed: src/domain/value.ts may only import src/domain/**`, Severity: severityError},
+		},
+		"src/infra/db.ts":           nil,
+		"packages/ui/src/button.ts": {{Line: 1, Message: `Import of "@acme/db/client" is not allowed: packages/ui may not import @acme/db`, Severity: severityError}},
+	}
+
+	for name, want := range expected {
+		path := filepath.Join(tempDir, name)
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", name, err)
+		}
+
+		result, err := analyzeContent(content, config.Rules, path)
+		if err != nil {
+			t.Fatalf("Failed to analyze %s: %v", name, err)
+		}
+		findings := result.Findings
+		if !reflect.DeepEqual(findings, want) {
+			t.Errorf("%s: expected %v, got %v", name, want, findings)
+		}
+	}
+
+	invalid := []*Rule{
+		{Type: ruleTypeBoundary, From: globList{"src/**"}},
+		{Type: ruleTypeBoundary, Deny: globList{"src/[a"}},
+	}
+	for i, rule := range invalid {
+		if err := rule.validate(); err == nil {
+			t.Errorf("Expected invalid rule %d to be rejected", i)
+		}
+	}
+}
+
+func TestImportResolutionFingerprint(t *testing.T) {
+	tempDir := t.TempDir()
+	writeFiles(t, tempDir, map[string]string{
+		"src/domain/user.ts": "import { db } from '../infra/db';\nexport function load() {}\n",
+	})
+	files := []string{filepath.Join(tempDir, "src/domain/user.ts")}
+
+	before, err := importResolutionFingerprint(files)
+	if err != nil {
+		t.Fatalf("Failed to fingerprint imports: %v", err)
+	}
+	if again, _ := importResolutionFingerprint(files); !reflect.DeepEqual(again, before) {
+		t.Error("Expected the same fingerprint when nothing changes")
+	}
+
+	// The import now resolves, which can change boundary findings although
+	// the checked file did not change
+	writeFiles(t, tempDir, map[string]string{"src/infra/db.ts": "export const db = {};\n"})
+	after, err := importResolutionFingerprint(files)
+	if err != nil {
+		t.Fatalf("Failed to fingerprint imports: %v", err)
+	}
+	if reflect.DeepEqual(after, before) {
+		t.Error("Expected a different fingerprint when an imported file is added")
+	}
+
+	if err := os.Remove(filepath.Join(tempDir, "src/infra/db.ts")); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+	if removed, _ := importResolutionFingerprint(files); !reflect.DeepEqual(removed, before) {
+		t.Error("Expected the original fingerprint once the imported file is removed")
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+
+	"github.com/smacker/go-tree-sitter/typescript/typescript"
+)
+
+// Bump when the cache entry format changes
+const cacheFormatVersion = "3"
+
+// resultCache stores the findings for each file on disk, keyed by a hash of
+// everything that can change them: the file path and content, the rules and
+// the scripts and plugins they load, the grammar and the analyzer binary
+type resultCache struct {
+	dir string
+
+	// Hash of everything except the file, computed once per run
+	runKey []byte
+
+	// Data added with extendRunKey, kept for caches derived with forRules
+	extensions [][]byte
+}
+
+// fileResult is what checking a file found, stored as JSON for each file
+type fileResult struct {
+	Findings []Finding `json:"findings"`
+
+	// First syntax error of the file, nil when it parsed cleanly
+	SyntaxError *syntaxError `json:"syntax_error,omitempty"`
+}
+
+// newResultCache opens the cache in dir, creating it if needed
+func newResultCache(dir string, rules []*Rule) (*resultCache, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(absDir, 0755); err != nil {
+		return nil, err
+	}
+
+	runKey, err := cacheRunKey(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	return &resultCache{dir: absDir, runKey: runKey}, nil
+}
+
+// cacheRunKey hashes the rules, grammar and analyzer version
+func cacheRunKey(rules []*Rule) ([]byte, error) {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "format %s\n", cacheFormatVersion)
+
+	// Every exported rule option is part of the key
+	ruleJSON, err := json.Marshal(rules)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(hash, "rules %s\n", ruleJSON)
+
+	// So is the unexported state results depend on: the same options written
+	// in another directory resolve import-boundary globs differently
+	for _, rule := range rules {
+		fmt.Fprintf(hash, "rule %q %q\n", rule.baseDir, rule.origin)
+	}
+
+	// Scripts and plugins can change without the rule options changing
+	for _, rule := range rules {
+		for _, path := range []string{rule.Script, rule.Plugin} {
+			if path == "" {
+				continue
+			}
+			if err := hashFile(hash, path); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	fmt.Fprintf(hash, "grammar %s %d\n", moduleVersion("github.com/smacker/go-tree-sitter"), typescript.GetLanguage().SymbolCount())
+
+	// The analyzer binary covers code changes in development builds too
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	if err := hashFile(hash, executable); err != nil {
+		return nil, err
+	}
+
+	return hash.Sum(nil), nil
+}
+
+// hashFile adds the path and content of a file to the hash
+func hashFile(hash io.Writer, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	fmt.Fprintf(hash, "file %s\n", path)
+	_, err = io.Copy(hash, file)
+	return err
+}
+
+// hashFiles hashes the path and content of every file, in sorted order
+func hashFiles(paths []string) ([]byte, error) {
+	sorted := append([]string(nil), paths...)
+	sort.Strings(sorted)
+
+	hash := sha256.New()
+	for _, path := range sorted {
+		if err := hashFile(hash, path); err != nil {
+			return nil, err
+		}
+	}
+	return hash.Sum(nil), nil
+}
+
+// moduleVersion returns the version of a dependency the binary was built with
+func moduleVersion(path string) string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == path {
+			return dep.Version
+		}
+	}
+	return "unknown"
+}
+
+// extendRunKey adds data that every file's results depend on to the run key
+func (c *resultCache) extendRunKey(data []byte) {
+	hash := sha256.New()
+	hash.Write(c.runKey)
+	hash.Write(data)
+	c.runKey = hash.Sum(nil)
+	c.extensions = append(c.extensions, data)
+}
+
+// forRules returns a cache in the same directory for files checked with a
+// different set of rules
+func (c *resultCache) forRules(rules []*Rule) (*resultCache, error) {
+	runKey, err := cacheRunKey(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	derived := &resultCache{dir: c.dir, runKey: runKey}
+	for _, data := range c.extensions {
+		derived.extendRunKey(data)
+	}
+	return derived, nil
+}
+
+// key returns the cache key of a file
+func (c *resultCache) key(path string, content []byte) string {
+	hash := sha256.New()
+	hash.Write(c.runKey)
+	fmt.Fprintf(hash, "path %s\n", path)
+	hash.Write(content)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// entryPath returns where the entry for a key is stored
+func (c *resultCache) entryPath(key string) string {
+	return filepath.Join(c.dir, key[:2], key+".json")
+}
+
+// get returns the cached result for a key
+func (c *resultCache) get(key string) (*fileResult, bool) {
+	data, err := os.ReadFile(c.entryPath(key))
+	if err != nil {
+		return nil, false
+	}
+
+	var result fileResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, false
+	}
+	return &result, true
+}
+
+// put stores the result for a key. The entry is written to a temporary file
+// and renamed so concurrent runs never read a partial entry.
+func (c *resultCache) put(key string, result *fileResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	path := c.entryPath(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "entry-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestResultCacheKey(t *testing.T) {
+	tempDir := t.TempDir()
+	scriptPath := filepath.Join(tempDir, "rule.star")
+	if err := os.WriteFile(scriptPath, []byte("def check(fn):\n    pass\n"), 0644); err != nil {
+		t.Fatalf("Failed to write script: %v", err)
+	}
+
+	newRules := func() []*Rule {
+		return []*Rule{
+			newCodeBlockRule("getContext()", false, false, "exported"),
+			{Type: ruleTypeScript, Script: scriptPath},
+		}
+	}
+
+	keyFor := func(rules []*Rule, content string) string {
+		t.Helper()
+		cache, err := newResultCache(filepath.Join(tempDir, "cache"), rules)
+		if err != nil {
+			t.Fatalf("Failed to open cache: %v", err)
+		}
+		return cache.key("/src/a.ts", []byte(content))
+	}
+
+	base := keyFor(newRules(), "export function a() {}")
+	if again := keyFor(newRules(), "export function a() {}"); again != base {
+		t.Error("Expected the same key for the same content and rules")
+	}
+
+	if keyFor(newRules(), "export function b() {}") == base {
+		t.Error("Expected a different key when the content changes")
+	}
+
+	inverted := newRules()
+	inverted[0].Invert = true
+	if keyFor(inverted, "export function a() {}") == base {
+		t.Error("Expected a different key when a rule option changes")
+	}
+
+	filtered := newRules()
+	filtered[0].NamePattern = "^a"
+	if keyFor(filtered, "export function a() {}") == base {
+		t.Error("Expected a different key when a function filter changes")
+	}
+
+	// The same config text in another directory resolves globs elsewhere
+	boundaryKey := func(dir string) string {
+		t.Helper()
+		writeFiles(t, tempDir, map[string]string{dir + "/config.yaml": "rules:\n  - type: import-boundary\n    deny: src/infra\n"})
+		config, err := loadConfig(filepath.Join(tempDir, dir, "config.yaml"))
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		return keyFor(config.Rules, "export function a() {}")
+	}
+	if boundaryKey("a") == boundaryKey("b") {
+		t.Error("Expected a different key for the same config in another directory")
+	}
+
+	if err := os.WriteFile(scriptPath, []byte("def check(fn):\n    report(\"x\")\n"), 0644); err != nil {
+		t.Fatalf("Failed to write script: %v", err)
+	}
+	if keyFor(newRules(), "export function a() {}") == base {
+		t.Error("Expected a different key when a script changes")
+	}
+}
+
+func TestProcessTypeScriptFileUsesCache(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "test.ts")
+	if err := os.WriteFile(testFile, []byte("export function a() {\n    return 1;\n}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	rule, err := compileCodeBlockRule("getContext()", false, false, "exported")
+	if err != nil {
+		t.Fatalf("Failed to compile rule: %v", err)
+	}
+	rules := []*Rule{rule}
+
+	cache, err := newResultCache(filepath.Join(tempDir, "cache"), rules)
+	if err != nil {
+		t.Fatalf("Failed to open cache: %v", err)
+	}
+
+	valid, issues := processTypeScriptFile(testFile, rules, cache)
+	if valid || issues != 1 {
+		t.Fatalf("Expected 1 issue on the first run, got valid=%v issues=%d", valid, issues)
+	}
+
+	content, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatalf("Failed to read test file: %v", err)
+	}
+	key := cache.key(testFile, content)
+
+	result, ok := cache.get(key)
+	if !ok {
+		t.Fatal("Expected the first run to store its findings")
+	}
+	expected := []Finding{{Line: 1, Message: "Missing required code block", Severity: severityError}}
+	if !reflect.DeepEqual(result.Findings, expected) {
+		t.Errorf("Expected cached %v, got %v", expected, result.Findings)
+	}
+
+	// A cache hit is reported without analyzing the file again
+	if err := cache.put(key, &fileResult{}); err != nil {
+		t.Fatalf("Failed to write cache entry: %v", err)
+	}
+	valid, issues = processTypeScriptFile(testFile, rules, cache)
+	if !valid || issues != 0 {
+		t.Errorf("Expected the cached result to be used, got valid=%v issues=%d", valid, issues)
+	}
+}
+
+func TestCheckFileRuleErrorsAreNotCached(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "test.ts")
+	content := []byte("export function a() {\n    return 1;\n}\n")
+	if err := os.WriteFile(testFile, content, 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	scriptPath := filepath.Join(tempDir, "divide.star")
+	if err := os.WriteFile(scriptPath, []byte("def check(fn):\n    return 1 // 0\n"), 0644); err != nil {
+		t.Fatalf("Failed to write script: %v", err)
+	}
+
+	when := newCodeBlockRule("getContext()", false, false, "exported")
+	when.When = "fn.param_types[0] == 'Context'"
+
+	testCases := map[string]*Rule{
+		"script": {Type: ruleTypeScript, Script: scriptPath, FnTypes: "exported"},
+		"when":   when,
+	}
+
+	for name, rule := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if err := rule.validate(); err != nil {
+				t.Fatalf("Unexpected validation error: %v", err)
+			}
+			rules := []*Rule{rule}
+
+			cache, err := newResultCache(filepath.Join(tempDir, "cache-"+name), rules)
+			if err != nil {
+				t.Fatalf("Failed to open cache: %v", err)
+			}
+
+			if _, _, err := checkFile(testFile, rules, cache); err == nil {
+				t.Fatal("Expected an error when the rule fails at runtime")
+			}
+			if _, ok := cache.get(cache.key(testFile, content)); ok {
+				t.Error("Expected a failed check not to be cached")
+			}
+		})
+	}
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/typescript/typescript"
+)
+
+// Default number of calls followed by transitive rules
+const defaultTransitiveDepth = 3
+
+// callGraph indexes every analyzed file so calls can be followed across files
+type callGraph struct {
+	files map[string]*graphFile
+}
+
+// graphFile is a parsed file in the call graph
+type graphFile struct {
+	path    string
+	content []byte
+	tree    *sitter.Tree
+	module  *moduleInfo
+}
+
+// graphFunction is a function found while following calls
+type graphFunction struct {
+	file *graphFile
+	node *sitter.Node
+	name string
+}
+
+// String formats the function as it appears in a resolution chain
+func (fn graphFunction) String() string {
+	return fmt.Sprintf("%s (%s:%d)", fn.name, fn.file.path, fn.node.StartPoint().Row+1)
+}
+
+// buildCallGraph parses every file. Paths are made absolute so they match
+// the paths used when reporting.
+func buildCallGraph(files []string) (*callGraph, error) {
+	graph := &callGraph{files: make(map[string]*graphFile)}
+
+	parser := sitter.NewParser()
+	parser.SetLanguage(typescript.GetLanguage())
+
+	for _, file := range files {
+		absPath, err := filepath.Abs(file)
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := os.ReadFile(absPath)
+		if err != nil {
+			return nil, err
+		}
+
+		tree := parser.Parse(nil, content)
+		graph.files[absPath] = &graphFile{
+			path:    absPath,
+			content: content,
+			tree:    tree,
+			module:  parseModuleInfo(tree.RootNode(), content),
+		}
+	}
+
+	return graph, nil
+}
+
+// fingerprint hashes the content of every file, since transitive results
+// depend on files other than the one being checked
+func (g *callGraph) fingerprint() []byte {
+	paths := make([]string, 0, len(g.files))
+	for path := range g.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	hash := sha256.New()
+	for _, path := range paths {
+		fmt.Fprintf(hash, "file %s %d\n", path, len(g.files[path].content))
+		hash.Write(g.files[path].content)
+	}
+	return hash.Sum(nil)
+}
+
+// function returns a function node of a file in the graph as a graphFunction
+func (g *callGraph) function(path string, funcNode *sitter.Node, content []byte) (graphFunction, bool) {
+	file, ok := g.files[path]
+	if !ok {
+		return graphFunction{}, false
+	}
+	return graphFunction{file: file, node: funcNode, name: functionName(funcNode, content)}, true
+}
+
+// callees returns the functions a function calls that can be resolved to a
+// function in the graph, in source order and without duplicates
+func (g *callGraph) callees(fn graphFunction) []graphFunction {
+	var callees []graphFunction
+	seen := make(map[string]bool)
+
+	var walk func(node *sitter.Node)
+	walk = func(node *sitter.Node) {
+		for i := 0; i < int(node.NamedChildCount()); i++ {
+			child := node.NamedChild(i)
+			if child.Type() == "call_expression" {
+				if callee, ok := g.resolveCall(fn, child); ok {
+					key := fmt.Sprintf("%s:%d", callee.file.path, callee.node.StartByte())
+					if !seen[key] {
+						seen[key] = true
+						callees = append(callees, callee)
+					}
+				}
+			}
+			walk(child)
+		}
+	}
+	walk(fn.node)
+
+	return callees
+}
+
+// resolveCall resolves the function called by a call expression
+func (g *callGraph) resolveCall(caller graphFunction, call *sitter.Node) (graphFunction, bool) {
+	function := call.ChildByFieldName("function")
+	if function == nil {
+		return graphFunction{}, false
+	}
+
+	content := caller.file.content
+	switch function.Type() {
+	case "identifier":
+		return g.resolveName(caller.file, nodeText(function, content))
+	case "member_expression":
+		object := function.ChildByFieldName("object")
+		property := function.ChildByFieldName("property")
+		if object == nil || property == nil {
+			return graphFunction{}, false
+		}
+		name := nodeText(property, content)
+
+		// this.method() calls a method of the same class
+		if object.Type() == "this" {
+			return g.resolveMethod(caller, name)
+		}
+
+		// ns.fn() calls an export of a namespace import
+		if object.Type() == "identifier" {
+			for _, binding := range caller.file.module.imports {
+				if binding.Imported == "*" && binding.Local == nodeText(object, content) {
+					if path, ok := g.resolveSource(caller.file.path, binding.Source); ok {
+						return g.resolveExport(path, name)
+					}
+				}
+			}
+		}
+	}
+
+	return graphFunction{}, false
+}
+
+// resolveName resolves a name used in a file to a local or imported function
+func (g *callGraph) resolveName(file *graphFile, name string) (graphFunction, bool) {
+	if node, ok := file.module.functions[name]; ok {
+		return graphFunction{file: file, node: node, name: name}, true
+	}
+
+	for _, binding := range file.module.imports {
+		if binding.Local != name || binding.Imported == "*" {
+			continue
+		}
+		if path, ok := g.resolveSource(file.path, binding.Source); ok {
+			return g.resolveExport(path, binding.Imported)
+		}
+	}
+
+	return graphFunction{}, false
+}
+
+// resolveExport resolves a name exported by a file to its function, following re-exports
+func (g *callGraph) resolveExport(path string, name string) (graphFunction, bool) {
+	var fn graphFunction
+	resolver := exportResolver{module: g.module}
+	resolver.resolve(path, name, func(path string, local string) bool {
+		if local == "*" {
+			// A namespace, not a function
+			return true
+		}
+		file := g.files[path]
+		if node, ok := file.module.functions[local]; ok {
+			fn = graphFunction{file: file, node: node, name: name}
+			return true
+		}
+		return false
+	})
+	return fn, fn.node != nil
+}
+
+// module returns the parsed file at a path, or nil when it is not in the graph
+func (g *callGraph) module(path string) *moduleInfo {
+	if file, ok := g.files[path]; ok {
+		return file.module
+	}
+	return nil
+}
+
+// resolveMethod resolves this.name() to a method of the caller's class
+func (g *callGraph) resolveMethod(caller graphFunction, name string) (graphFunction, bool) {
+	class := enclosingClass(caller.node)
+	if class == nil {
+		return graphFunction{}, false
+	}
+
+	body := class.ChildByFieldName("body")
+	if body == nil {
+		return graphFunction{}, false
+	}
+
+	for i := 0; i < int(body.NamedChildCount()); i++ {
+		member := body.NamedChild(i)
+		if member.Type() != "method_definition" {
+			continue
+		}
+		if nameNode := member.ChildByFieldName("name"); nameNode != nil && nodeText(nameNode, caller.file.content) == name {
+			return graphFunction{file: caller.file, node: member, name: name}, true
+		}
+	}
+
+	return graphFunction{}, false
+}
+
+// resolveSource resolves a module specifier to a file in the graph
+func (g *callGraph) resolveSource(fromFile string, source string) (string, bool) {
+	path, ok := resolveModule(fromFile, source)
+	if !ok {
+		return "", false
+	}
+	if _, ok := g.files[path]; !ok {
+		return "", false
+	}
+	return path, true
+}
+
+// reaches reports whether every chain of calls starting at the callees of fn
+// reaches a function for which uses is true within depth calls. When it does
+// not, it returns the chain that fell short, starting at fn.
+func (g *callGraph) reaches(fn graphFunction, uses func(graphFunction) bool, depth int, visiting map[string]bool) (bool, []graphFunction) {
+	if depth == 0 {
+		return false, []graphFunction{fn}
+	}
+
+	key := fmt.Sprintf("%s:%d", fn.file.path, fn.node.StartByte())
+	if visiting[key] {
+		return false, []graphFunction{fn}
+	}
+	visiting[key] = true
+	defer delete(visiting, key)
+
+	callees := g.callees(fn)
+	if len(callees) == 0 {
+		return false, []graphFunction{fn}
+	}
+
+	for _, callee := range callees {
+		if uses(callee) {
+			continue
+		}
+		if ok, chain := g.reaches(callee, uses, depth-1, visiting); !ok {
+			return false, append([]graphFunction{fn}, chain...)
+		}
+	}
+
+	return true, nil
+}
+
+// formatChain formats a resolution chain for reports
+func formatChain(chain []graphFunction) string {
+	parts := make([]string, len(chain))
+	for i, fn := range chain {
+		parts[i] = fn.String()
+	}
+	return strings.Join(parts, " -> ")
+}
+
+// checkTransitive follows the calls of a function that does not use the code
+// block itself and reports the first chain of calls that does not reach it
+func (r *Rule) checkTransitive(funcNode *sitter.Node, content []byte, filename string) *Finding {
+	line := funcNode.StartPoint().Row + 1
+	missing := &Finding{Line: line, Message: "Missing required code block"}
+
+	if r.graph == nil {
+		return missing
+	}
+	fn, ok := r.graph.function(filename, funcNode, content)
+	if !ok {
+		return missing
+	}
+
+	uses := func(callee graphFunction) bool {
+		return r.usesCodeBlock(callee.node, callee.file.content, callee.file.path)
+	}
+
+	ok, chain := r.graph.reaches(fn, uses, r.Depth, make(map[string]bool))
+	if ok {
+		logger.Debug("code block reached through every call", "function", fn.String())
+		return nil
+	}
+
+	logger.Debug("code block not reached", "chain", formatChain(chain))
+
+	if len(chain) > 1 {
+		missing.Message = fmt.Sprintf("Missing required code block (not reached via %s)", formatChain(chain))
+	}
+	return missing
+}
+
+// hasTransitiveRule reports whether any rule needs the call graph
+func hasTransitiveRule(rules []*Rule) bool {
+	for _, rule := range rules {
+		if rule.Transitive {
+			return true
+		}
+	}
+	return false
+}
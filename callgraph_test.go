@@ -0,0 +1,169 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTransitiveRule(t *testing.T) {
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"handlers.ts": `import { loadUser } from './users';
+import * as audit from './audit';
+import helpers from './helpers';
+
+export function getUser(id: string) {
+    return loadUser(id);
+}
+
+export function deleteUser(id: string) {
+    audit.record(id);
+    return loadUser(id);
+}
+
+export function renameUser(id: string) {
+    return helpers(id);
+}
+
+export function deep(id: string) {
+    return level1(id);
+}
+
+function level1(id: string) { return level2(id); }
+function level2(id: string) { return level3(id); }
+function level3(id: string) { return level4(id); }
+function level4(id: string) { return getContext().user(id); }
+
+export function recursive(n: number) {
+    return recursive(n - 1);
+}
+
+export function unresolved() {
+    return fetch('/users');
+}
+
+export class UserService {
+    find(id: string) {
+        return this.load(id);
+    }
+
+    private load(id: string) {
+        const ctx = getContext();
+        return ctx.find(id);
+    }
+}
+`,
+		"users.ts": `export { loadUser } from './store';
+`,
+		"store.ts": `export const loadUser = (id: string) => {
+    const ctx = getContext();
+    return ctx.users.get(id);
+};
+`,
+		"audit/index.ts": `export function record(id: string) {
+    console.log(id);
+}
+`,
+		"helpers.ts": `export default function rename(id: string) {
+    return getContext().rename(id);
+}
+`,
+	}
+
+	var paths []string
+	for name, source := range files {
+		path := filepath.Join(tempDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		paths = append(paths, path)
+	}
+
+	graph, err := buildCallGraph(paths)
+	if err != nil {
+		t.Fatalf("Failed to build call graph: %v", err)
+	}
+
+	handlers := filepath.Join(tempDir, "handlers.ts")
+	content := []byte(files["handlers.ts"])
+
+	check := func(depth int) map[string]string {
+		t.Helper()
+		rule := newCodeBlockRule("getContext()", false, false, "exported")
+		rule.Transitive = true
+		rule.Depth = depth
+		if err := rule.validate(); err != nil {
+			t.Fatalf("Failed to validate rule: %v", err)
+		}
+		rule.graph = graph
+
+		result, err := analyzeContent(content, []*Rule{rule}, handlers)
+		if err != nil {
+			t.Fatalf("Failed to analyze: %v", err)
+		}
+		findings := result.Findings
+
+		lines := strings.Split(string(content), "\n")
+		byFunction := make(map[string]string)
+		for _, finding := range findings {
+			byFunction[strings.TrimSpace(lines[finding.Line-1])] = finding.Message
+		}
+		return byFunction
+	}
+
+	findings := check(0)
+	for _, compliant := range []string{"getUser", "renameUser", "find"} {
+		for line := range findings {
+			if strings.Contains(line, compliant+"(") {
+				t.Errorf("Expected %s to reach the code block, got %q", compliant, findings[line])
+			}
+		}
+	}
+
+	expected := map[string]string{
+		"export function deleteUser(id: string) {": "not reached via deleteUser (" + handlers + ":9) -> record (" + filepath.Join(tempDir, "audit/index.ts") + ":1)",
+		"export function deep(id: string) {":       "not reached via deep (" + handlers + ":18) -> level1",
+		"export function recursive(n: number) {":   "not reached via recursive (" + handlers + ":27) -> recursive",
+		"export function unresolved() {":           "Missing required code block",
+	}
+	for line, want := range expected {
+		got, ok := findings[line]
+		if !ok {
+			t.Errorf("Expected a finding for %q", line)
+			continue
+		}
+		if !strings.Contains(got, want) {
+			t.Errorf("Finding for %q = %q, expected it to contain %q", line, got, want)
+		}
+	}
+	if len(findings) != len(expected) {
+		t.Errorf("Expected %d findings, got %d: %v", len(expected), len(findings), findings)
+	}
+
+	// A deeper search reaches level4
+	if _, ok := check(4)["export function deep(id: string) {"]; ok {
+		t.Error("Expected deep to reach the code block with transitive-depth 4")
+	}
+}
+
+func TestTransitiveRuleValidation(t *testing.T) {
+	rule := newCodeBlockRule("getContext()", false, true, "exported")
+	rule.Transitive = true
+	if err := rule.validate(); err == nil {
+		t.Error("Expected transitive with invert to be rejected")
+	}
+
+	rule = newCodeBlockRule("getContext()", false, false, "exported")
+	rule.Transitive = true
+	if err := rule.validate(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if rule.Depth != defaultTransitiveDepth {
+		t.Errorf("Expected default depth %d, got %d", defaultTransitiveDepth, rule.Depth)
+	}
+}
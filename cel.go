@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/ext"
+)
+
+// newCELEnv creates the environment CEL conditions are compiled in. The
+// function being checked is available as `fn`.
+func newCELEnv() (*cel.Env, error) {
+	factsType := reflect.TypeOf(FunctionFacts{})
+
+	// Native types are named after the last element of their package path,
+	// which is "main" in the binary but the module name under go test
+	pkgPath := factsType.PkgPath()
+	typeName := pkgPath[strings.LastIndex(pkgPath, "/")+1:] + "." + factsType.Name()
+
+	return cel.NewEnv(
+		ext.NativeTypes(factsType, ext.ParseStructTags(true)),
+		ext.Strings(),
+		cel.Variable("fn", cel.ObjectType(typeName)),
+	)
+}
+
+// compileCondition compiles a CEL expression that must evaluate to a bool
+func compileCondition(expression string) (cel.Program, error) {
+	env, err := newCELEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	if ast.OutputType() != cel.BoolType {
+		return nil, fmt.Errorf("expression must evaluate to a bool, got %s", ast.OutputType())
+	}
+
+	return env.Program(ast)
+}
+
+// evalCondition evaluates a compiled condition against a function
+func evalCondition(program cel.Program, facts *FunctionFacts) (bool, error) {
+	out, _, err := program.Eval(map[string]any{"fn": facts})
+	if err != nil {
+		return false, err
+	}
+
+	result, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("expression returned %v instead of a bool", out.Value())
+	}
+	return result, nil
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is a ts-analyzer configuration file merged with the files it extends
+type Config struct {
+	// Rules of the file when it inherits none, as with -config. Nil for the
+	// config files of subdirectories, whose rules depend on the directories above.
+	Rules []*Rule
+
+	// Changes to the rules for some of the files
+	Overrides []*Override
+
+	// Do not inherit rules from the directories above
+	Root bool
+
+	// How the file changes the rules it inherits: the extended configs are
+	// merged in order, and then the file's own rules, disable and enable apply
+	extended []*Config
+	own      *Override
+}
+
+// configFile is the content of a configuration file as written, whether it
+// is passed with -config or sits in a subdirectory
+type configFile struct {
+	// Do not inherit rules from the directories above
+	Root bool `yaml:"root"`
+
+	// Files, directories of files and built-in presets merged before this file
+	Extends stringList `yaml:"extends"`
+
+	Rules []yaml.Node `yaml:"rules"`
+
+	// Plugins are rules of type plugin, listed separately for readability
+	Plugins []yaml.Node `yaml:"plugins"`
+
+	// Names of inherited or extended rules to disable, or enable again
+	Disable []string `yaml:"disable"`
+	Enable  []string `yaml:"enable"`
+
+	Overrides []*Override `yaml:"overrides"`
+}
+
+// stringList is a list of strings that can also be written as a single string
+type stringList []string
+
+// UnmarshalYAML accepts a single string as well as a list
+func (s *stringList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*s = stringList{value.Value}
+		return nil
+	}
+
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	*s = list
+	return nil
+}
+
+// loadConfig reads and validates the configuration file at path, which
+// inherits no rules
+func loadConfig(path string) (*Config, error) {
+	return loadExtendedConfig(path, nil)
+}
+
+// loadExtendedConfig loads a configuration file or built-in preset that
+// inherits no rules, with the rules it ends up with. chain holds the files
+// being loaded, so cycles are reported instead of followed.
+func loadExtendedConfig(path string, chain []string) (*Config, error) {
+	config, err := readConfig(path, chain)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := config.apply(nil)
+	if err != nil {
+		return nil, err
+	}
+	config.Rules = []*Rule{}
+	for _, entry := range entries {
+		if !entry.disabled {
+			config.Rules = append(config.Rules, entry.rule)
+		}
+	}
+	return config, nil
+}
+
+// loadDirectoryConfig reads the config file at path, which changes the rules
+// of the files under its directory, or returns nil when it does not exist
+func loadDirectoryConfig(path string) (*Config, error) {
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return readConfig(path, nil)
+}
+
+// readConfig reads a configuration file or built-in preset and loads the
+// files it extends, without applying its rules to any
+func readConfig(path string, chain []string) (*Config, error) {
+	id := path
+	if !isPreset(path) {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		id = absPath
+	}
+	for _, loading := range chain {
+		if loading == id {
+			return nil, fmt.Errorf("extends cycle: %s", strings.Join(append(chain, id), " -> "))
+		}
+	}
+	chain = append(chain, id)
+
+	data, err := readConfigSource(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file configFile
+	if err := decodeStrict(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	config := &Config{Root: file.Root}
+	for _, entry := range file.Extends {
+		paths, err := extendedPaths(entry, filepath.Dir(path))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for _, extendedPath := range paths {
+			extended, err := loadExtendedConfig(extendedPath, chain)
+			if err != nil {
+				return nil, err
+			}
+			config.extended = append(config.extended, extended)
+			config.Overrides = append(config.Overrides, extended.Overrides...)
+		}
+	}
+
+	// The file's own rules change the rules it inherits like an override of every file
+	config.own = &Override{Rules: file.Rules, Disable: file.Disable, Enable: file.Enable, dir: filepath.Dir(path), origin: path}
+	for _, node := range file.Plugins {
+		config.own.Rules = append(config.own.Rules, withRuleType(node, ruleTypePlugin))
+	}
+	if err := config.own.validate(); err != nil {
+		return nil, err
+	}
+
+	// Override rules are validated once it is known which rules they change
+	if err := validateOverrides(file.Overrides, path); err != nil {
+		return nil, err
+	}
+	config.Overrides = append(config.Overrides, file.Overrides...)
+
+	return config, nil
+}
+
+// apply returns the rules of a file after the config file. The rules of the
+// files it extends take the place of the rules with the same name or are
+// added, and then its own rules reconfigure or add to them.
+func (c *Config) apply(entries []ruleEntry) ([]ruleEntry, error) {
+	entries = append([]ruleEntry(nil), entries...)
+	for _, extended := range c.extended {
+		for _, rule := range extended.Rules {
+			entries = mergeRule(entries, rule)
+		}
+	}
+	return c.own.apply(entries)
+}
+
+// mergeRule replaces the entry of the rule with the same name, or adds the rule
+func mergeRule(entries []ruleEntry, rule *Rule) []ruleEntry {
+	if rule.Name != "" {
+		for i, entry := range entries {
+			if entry.rule.Name == rule.Name {
+				entries[i] = ruleEntry{rule: rule}
+				return entries
+			}
+		}
+	}
+	return append(entries, ruleEntry{rule: rule})
+}
+
+// extendedPaths returns the config files an extends entry refers to: a
+// built-in preset, a file, or every YAML file of a directory in name order.
+// Paths are relative to the directory of the extending file.
+func extendedPaths(entry string, dir string) ([]string, error) {
+	if isPreset(entry) {
+		return []string{entry}, nil
+	}
+
+	path := entry
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("extends %q: %w", entry, err)
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	var paths []string
+	for _, pattern := range []string{"*.yaml", "*.yml"} {
+		matches, err := filepath.Glob(filepath.Join(path, pattern))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, matches...)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("extends %q: no YAML files in the directory", entry)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// withRuleType returns a copy of a rule's node with its type set
+func withRuleType(node yaml.Node, ruleType string) yaml.Node {
+	content := make([]*yaml.Node, 0, len(node.Content)+2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != "type" {
+			content = append(content, node.Content[i], node.Content[i+1])
+		}
+	}
+	node.Content = append(content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "type"},
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: ruleType})
+	return node
+}
+
+// resolveRulePaths makes the paths of a rule relative to the directory of
+// the config file it is written in
+func resolveRulePaths(rule *Rule, dir string) error {
+	// Scripts and plugins are loaded relative to the configuration file
+	if rule.Script != "" && !filepath.IsAbs(rule.Script) {
+		rule.Script = filepath.Join(dir, rule.Script)
+	}
+	if rule.Plugin != "" && !filepath.IsAbs(rule.Plugin) {
+		rule.Plugin = filepath.Join(dir, rule.Plugin)
+	}
+
+	// So are the globs of import-boundary rules
+	if rule.Type == ruleTypeBoundary {
+		absDir, err := filepath.Abs(dir)
+		if err != nil {
+			return err
+		}
+		rule.baseDir = absDir
+	}
+	return nil
+}
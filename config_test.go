@@ -0,0 +1,85 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigExtends(t *testing.T) {
+	tempDir := t.TempDir()
+	writeFiles(t, tempDir, map[string]string{
+		"presets/a.yaml": `rules:
+  - name: context
+    code-block: getContext()
+  - name: audit
+    code-block: audit(
+overrides:
+  - files: src/scripts/**
+    disable: [audit]
+`,
+		"presets/b.yml": `rules:
+  - name: audit
+    code-block: audit.log(
+`,
+		"shared/base.yaml": `extends: [ts-analyzer:no-console]
+rules:
+  - name: no-console
+    fn-types: exported,internal
+`,
+		"config.yaml": `extends:
+  - presets
+  - shared/base.yaml
+rules:
+  - name: context
+    severity: warning
+  - code-block: tracked(
+plugins:
+  - name: context
+    plugin: missing.wasm
+`,
+	})
+
+	// A plugin with the name of an extended rule reconfigures it, and must exist
+	if _, err := loadConfig(filepath.Join(tempDir, "config.yaml")); err == nil || !strings.Contains(err.Error(), "missing.wasm") {
+		t.Fatalf("Expected the plugin to be loaded, got %v", err)
+	}
+
+	writeFiles(t, tempDir, map[string]string{
+		"config.yaml": `extends:
+  - presets
+  - shared/base.yaml
+rules:
+  - name: context
+    severity: warning
+  - code-block: tracked(
+`,
+	})
+	config, err := loadConfig(filepath.Join(tempDir, "config.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	var rules []string
+	for _, rule := range config.Rules {
+		rules = append(rules, rule.Name+":"+rule.CodeBlock+":"+rule.FnTypes+":"+rule.Severity)
+	}
+	expected := "context:getContext():exported:warning audit:audit.log(:exported:error " +
+		`no-console:\bconsole\.[a-z]+\(:exported,internal:error :tracked(:exported:error`
+	if got := strings.Join(rules, " "); got != expected {
+		t.Errorf("Expected rules %q, got %q", expected, got)
+	}
+
+	// Overrides of extended files keep their globs relative to them
+	if len(config.Overrides) != 1 || !config.Overrides[0].matches(filepath.Join(tempDir, "presets/src/scripts/run.ts")) {
+		t.Errorf("Expected the override of presets/a.yaml, got %v", config.Overrides)
+	}
+
+	writeFiles(t, tempDir, map[string]string{
+		"cycle/a.yaml": "extends: b.yaml\n",
+		"cycle/b.yaml": "extends: a.yaml\n",
+	})
+	if _, err := loadConfig(filepath.Join(tempDir, "cycle/a.yaml")); err == nil || !strings.Contains(err.Error(), "extends cycle") {
+		t.Errorf("Expected an extends cycle error, got %v", err)
+	}
+}
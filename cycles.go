@@ -0,0 +1,253 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/typescript/typescript"
+)
+
+// moduleGraph is the graph of static imports between the analyzed files
+type moduleGraph struct {
+	files []string                // Absolute and sorted
+	edges map[string][]moduleEdge // By importing file, in source order
+
+	// Findings for every file in a cycle, computed on first use
+	once     sync.Once
+	findings map[string][]Finding
+}
+
+// moduleEdge is an import of one analyzed file by another
+type moduleEdge struct {
+	target string
+	line   uint32
+}
+
+// buildModuleGraph parses every file and resolves its import and export-from
+// statements. Type-only imports are left out since they are erased at
+// compile time and cannot cause initialization-order problems.
+func buildModuleGraph(files []string) (*moduleGraph, error) {
+	graph := &moduleGraph{edges: make(map[string][]moduleEdge)}
+
+	known := make(map[string]bool)
+	for _, file := range files {
+		absPath, err := filepath.Abs(file)
+		if err != nil {
+			return nil, err
+		}
+		if !known[absPath] {
+			known[absPath] = true
+			graph.files = append(graph.files, absPath)
+		}
+	}
+	sort.Strings(graph.files)
+
+	parser := sitter.NewParser()
+	parser.SetLanguage(typescript.GetLanguage())
+
+	for _, file := range graph.files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		rootNode := parser.Parse(nil, content).RootNode()
+
+		for i := 0; i < int(rootNode.NamedChildCount()); i++ {
+			statement := rootNode.NamedChild(i)
+			if statement.Type() != "import_statement" && statement.Type() != "export_statement" {
+				continue
+			}
+			source := statement.ChildByFieldName("source")
+			if source == nil || isTypeOnlyStatement(statement) {
+				continue
+			}
+
+			target, ok := resolveModule(file, stringLiteralValue(source, content))
+			if !ok || !known[target] {
+				continue
+			}
+			graph.edges[file] = append(graph.edges[file], moduleEdge{target: target, line: statement.StartPoint().Row + 1})
+		}
+	}
+
+	return graph, nil
+}
+
+// isTypeOnlyStatement reports whether a statement is `import type` or `export type`
+func isTypeOnlyStatement(statement *sitter.Node) bool {
+	for i := 0; i < int(statement.ChildCount()); i++ {
+		child := statement.Child(i)
+		if child.Type() == "type" && !child.IsNamed() {
+			return true
+		}
+		if child.Type() == "import_clause" || child.Type() == "export_clause" || child.Type() == "string" {
+			break
+		}
+	}
+	return false
+}
+
+// stronglyConnected returns the strongly connected components of the graph
+// with Tarjan's algorithm, each sorted, in a stable order
+func (g *moduleGraph) stronglyConnected() [][]string {
+	index := make(map[string]int)
+	lowLink := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var components [][]string
+	next := 0
+
+	var visit func(file string)
+	visit = func(file string) {
+		index[file] = next
+		lowLink[file] = next
+		next++
+		stack = append(stack, file)
+		onStack[file] = true
+
+		for _, edge := range g.edges[file] {
+			if _, seen := index[edge.target]; !seen {
+				visit(edge.target)
+				lowLink[file] = min(lowLink[file], lowLink[edge.target])
+			} else if onStack[edge.target] {
+				lowLink[file] = min(lowLink[file], index[edge.target])
+			}
+		}
+
+		if lowLink[file] == index[file] {
+			var component []string
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component = append(component, top)
+				if top == file {
+					break
+				}
+			}
+			sort.Strings(component)
+			components = append(components, component)
+		}
+	}
+
+	for _, file := range g.files {
+		if _, seen := index[file]; !seen {
+			visit(file)
+		}
+	}
+
+	sort.Slice(components, func(i, j int) bool {
+		return components[i][0] < components[j][0]
+	})
+	return components
+}
+
+// cycleFindings returns the findings of a file: one for every import that
+// leads to a file in the same cycle, showing the shortest way back
+func (g *moduleGraph) cycleFindings(file string) []Finding {
+	g.once.Do(g.computeFindings)
+	return g.findings[file]
+}
+
+// computeFindings finds the cycles in every strongly connected component
+func (g *moduleGraph) computeFindings() {
+	g.findings = make(map[string][]Finding)
+
+	for _, component := range g.stronglyConnected() {
+		members := make(map[string]bool, len(component))
+		for _, file := range component {
+			members[file] = true
+		}
+
+		for _, file := range component {
+			for _, edge := range g.edges[file] {
+				if !members[edge.target] {
+					continue
+				}
+				// A single file only forms a cycle when it imports itself
+				if len(component) == 1 && edge.target != file {
+					continue
+				}
+
+				cycle := append([]string{file}, g.shortestPath(edge.target, file, members)...)
+				g.findings[file] = append(g.findings[file], Finding{
+					Line:    edge.line,
+					Message: "Import cycle: " + formatCycle(cycle),
+				})
+			}
+		}
+	}
+}
+
+// shortestPath returns the files from one file to another, both included,
+// using only imports between members
+func (g *moduleGraph) shortestPath(from string, to string, members map[string]bool) []string {
+	if from == to {
+		return []string{to}
+	}
+
+	previous := map[string]string{from: ""}
+	queue := []string{from}
+	for len(queue) > 0 {
+		file := queue[0]
+		queue = queue[1:]
+
+		for _, edge := range g.edges[file] {
+			if _, seen := previous[edge.target]; seen || !members[edge.target] {
+				continue
+			}
+			previous[edge.target] = file
+			if edge.target == to {
+				var path []string
+				for current := to; current != ""; current = previous[current] {
+					path = append([]string{current}, path...)
+				}
+				return path
+			}
+			queue = append(queue, edge.target)
+		}
+	}
+
+	// Unreachable within a strongly connected component
+	return []string{from, to}
+}
+
+// formatCycle formats a cycle with paths relative to the working directory
+func formatCycle(cycle []string) string {
+	dir, _ := os.Getwd()
+	parts := make([]string, len(cycle))
+	for i, file := range cycle {
+		parts[i] = file
+		if relative, err := filepath.Rel(dir, file); err == nil && !strings.HasPrefix(relative, "..") {
+			parts[i] = relative
+		}
+	}
+	return strings.Join(parts, " -> ")
+}
+
+// hasCyclesRule reports whether any rule needs the module graph
+func hasCyclesRule(rules []*Rule) bool {
+	for _, rule := range rules {
+		if rule.Type == ruleTypeCycles {
+			return true
+		}
+	}
+	return false
+}
+
+// checkCycles reports the imports of a file that are part of a cycle
+func (r *Rule) checkCycles(filename string) []Finding {
+	if r.modules == nil {
+		return nil
+	}
+
+	findings := r.modules.cycleFindings(filename)
+	if len(findings) > 0 {
+		logger.Debug("file is part of import cycles", "file", filename, "imports", len(findings))
+	}
+	return findings
+}
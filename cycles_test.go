@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestImportCycles(t *testing.T) {
+	tempDir := t.TempDir()
+	writeFiles(t, tempDir, map[string]string{
+		"a.ts":      "import { b } from './b';\nexport const a = () => b();\n",
+		"b.ts":      "import { log } from 'logger';\n\nexport { c } from './c';\nexport const b = () => 1;\n",
+		"c.ts":      "import type { B } from './b';\nimport { a } from './a';\nexport const c = a;\n",
+		"d.ts":      "import { a } from './a';\nimport { d2 } from './d';\n",
+		"e.ts":      "import type { F } from './f';\n",
+		"f.ts":      "import { e } from './e';\n",
+		"types.ts":  "export type { Self } from './types';\n",
+		"unused.ts": "import { a } from './missing';\n",
+	})
+
+	var files []string
+	for _, name := range []string{"a.ts", "b.ts", "c.ts", "d.ts", "e.ts", "f.ts", "types.ts", "unused.ts"} {
+		files = append(files, filepath.Join(tempDir, name))
+	}
+	graph, err := buildModuleGraph(files)
+	if err != nil {
+		t.Fatalf("Failed to build module graph: %v", err)
+	}
+
+	// Paths in the messages are relative to the working directory
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+
+	expected := map[string][]Finding{
+		"a.ts": {{Line: 1, Message: "Import cycle: a.ts -> b.ts -> c.ts -> a.ts"}},
+		"b.ts": {{Line: 3, Message: "Import cycle: b.ts -> c.ts -> a.ts -> b.ts"}},
+		"c.ts": {{Line: 2, Message: "Import cycle: c.ts -> a.ts -> b.ts -> c.ts"}},
+		"d.ts": {{Line: 2, Message: "Import cycle: d.ts -> d.ts"}},
+	}
+	for name, want := range expected {
+		if got := graph.cycleFindings(filepath.Join(tempDir, name)); !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected findings %v for %s, got %v", want, name, got)
+		}
+	}
+
+	// Type-only imports and imports of unknown files do not form cycles
+	for _, name := range []string{"e.ts", "f.ts", "types.ts", "unused.ts"} {
+		if got := graph.cycleFindings(filepath.Join(tempDir, name)); len(got) != 0 {
+			t.Errorf("Expected no findings for %s, got %v", name, got)
+		}
+	}
+
+	rule := &Rule{Type: ruleTypeCycles, modules: graph}
+	if err := rule.validate(); err != nil {
+		t.Fatalf("Expected a cycles rule to be valid: %v", err)
+	}
+	if findings, err := rule.checkFile(nil, nil, filepath.Join(tempDir, "a.ts")); !rule.fileLevel() || err != nil || len(findings) != 1 {
+		t.Error("Expected the cycles rule to report the import of a.ts")
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/typescript/typescript"
+)
+
+// Longest line text shown when explaining a match
+const maxExplainLineLength = 100
+
+// Words of a code block, compared when looking for near matches
+var wordPattern = regexp.MustCompile(`[A-Za-z_$][A-Za-z0-9_$]*`)
+
+// parseExplainTarget splits the file:line argument of -explain
+func parseExplainTarget(target string) (string, int, error) {
+	index := strings.LastIndex(target, ":")
+	if index <= 0 {
+		return "", 0, fmt.Errorf("invalid -explain %q, expected file:line", target)
+	}
+
+	line, err := strconv.Atoi(target[index+1:])
+	if err != nil || line < 1 {
+		return "", 0, fmt.Errorf("invalid line in -explain %q", target)
+	}
+	return target[:index], line, nil
+}
+
+// explainFunction describes how every rule treats the function at a line of
+// a file: the innermost function whose range contains it. It reports whether
+// the function passes every rule.
+func explainFunction(w io.Writer, filename string, line int, rules []*Rule) (bool, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return false, err
+	}
+
+	parser := sitter.NewParser()
+	parser.SetLanguage(typescript.GetLanguage())
+	rootNode := parser.Parse(nil, content).RootNode()
+
+	functions, err := listFunctions(rootNode, content, filename)
+	if err != nil {
+		return false, err
+	}
+
+	var fn *functionInfo
+	for i := range functions {
+		candidate := &functions[i]
+		if candidate.Line > line || candidate.EndLine < line {
+			continue
+		}
+		if fn == nil || candidate.EndLine-candidate.Line <= fn.EndLine-fn.Line {
+			fn = candidate
+		}
+	}
+	if fn == nil {
+		return false, fmt.Errorf("no function found at %s:%d", filename, line)
+	}
+
+	name := fn.Name
+	if name == "" {
+		name = "(anonymous)"
+	}
+	fmt.Fprintf(w, "Function %s (%s:%d-%d)\n", name, filename, fn.Line, fn.EndLine)
+	fmt.Fprintf(w, "  Selected by the queries for: %s\n", strings.Join(fn.Kinds, ", "))
+	fmt.Fprintf(w, "  Exported: %t, async: %t", fn.Exported, fn.Async)
+	if fn.Class != "" {
+		fmt.Fprintf(w, ", class: %s", fn.Class)
+	}
+	fmt.Fprintln(w)
+
+	if fn.Ignored {
+		fmt.Fprintf(w, "  Ignore directive: // @ts-analyzer-ignore on line %d, no rule is applied\n", fn.Line-1)
+		return true, nil
+	}
+	fmt.Fprintln(w, "  Ignore directive: none")
+
+	passed := true
+	for i, rule := range rules {
+		fmt.Fprintf(w, "\nRule %d: %s\n", i+1, describeRule(rule))
+		rulePassed, err := explainRule(w, rule, fn, rootNode, content, filename)
+		if err != nil {
+			return false, err
+		}
+		if !rulePassed {
+			passed = false
+		}
+	}
+
+	return passed, nil
+}
+
+// describeRule returns a short description of a rule
+func describeRule(r *Rule) string {
+	description := r.Type
+	switch r.Type {
+	case ruleTypeCodeBlock:
+		description = fmt.Sprintf("code-block %q", r.codeBlock.String())
+		if r.Regex {
+			description += " (regex)"
+		}
+		if r.Invert {
+			description += " (inverted)"
+		}
+	case ruleTypeOrder:
+		description = fmt.Sprintf("order, %q after %q", r.After.String(), r.Before.String())
+	case ruleTypeScript:
+		description = "script " + r.Script
+	case ruleTypePlugin:
+		description = "plugin " + r.Plugin
+	}
+
+	if r.Severity != "" && r.Severity != severityError {
+		description += " [" + r.Severity + "]"
+	}
+	if r.Name != "" {
+		description = r.Name + ": " + description
+	}
+	return description
+}
+
+// explainRule describes how a rule treats a function and reports whether
+// the function passes it, or an error when the rule cannot be applied
+func explainRule(w io.Writer, rule *Rule, fn *functionInfo, rootNode *sitter.Node, content []byte, filename string) (bool, error) {
+	if rule.fileLevel() {
+		fmt.Fprintln(w, "  Not applied: the rule checks whole files, not functions")
+		return true, nil
+	}
+
+	fnTypes := parseFunctionTypes(rule.FnTypes)
+	fnType := ""
+	for _, kind := range fn.Kinds {
+		if fnTypes[kind] {
+			fnType = kind
+			break
+		}
+	}
+	if fnType == "" {
+		fmt.Fprintf(w, "  Not applied: the rule checks %s functions\n", rule.FnTypes)
+		return true, nil
+	}
+	selected, err := rule.selects(fn.node, rootNode, content, fnType, filename)
+	if err != nil {
+		return false, err
+	}
+	if !selected {
+		fmt.Fprintln(w, "  Not applied: the function filters exclude it")
+		return true, nil
+	}
+	fmt.Fprintf(w, "  Applied as: %s\n", fnType)
+
+	switch rule.Type {
+	case ruleTypeCodeBlock:
+		explainPattern(w, "Code block", fn.node, content, filename, rule.codeBlock)
+	case ruleTypeOrder:
+		explainPattern(w, "Before", fn.node, content, filename, rule.Before)
+		explainPattern(w, "After", fn.node, content, filename, rule.After)
+	}
+
+	findings, err := rule.evaluate(fn.node, rootNode, content, fnType, filename)
+	if err != nil {
+		return false, err
+	}
+	if len(findings) == 0 {
+		fmt.Fprintln(w, "  Result: pass")
+		return true, nil
+	}
+	for _, finding := range findings {
+		fmt.Fprintf(w, "  Result: fail at line %d: %s\n", finding.Line, finding.Message)
+	}
+	return false, nil
+}
+
+// explainPattern lists the lines of a function that match a pattern, the
+// matches rejected because they are in comments, and the near matches
+func explainPattern(w io.Writer, label string, funcNode *sitter.Node, content []byte, filename string, p *Pattern) {
+	fmt.Fprintf(w, "  %s: %s\n", label, p.String())
+
+	// Query and import patterns are matched on the syntax tree, not on lines
+	if p.Text == "" {
+		occurrences := findOccurrences(funcNode, content, filename, p)
+		if len(occurrences) == 0 {
+			fmt.Fprintln(w, "    no matches")
+		}
+		for _, occurrence := range occurrences {
+			fmt.Fprintf(w, "    line %d: match\n", occurrence.line)
+		}
+		return
+	}
+
+	found := false
+	line := funcNode.StartPoint().Row + 1
+	for _, text := range strings.Split(nodeText(funcNode, content), "\n") {
+		trimmedLine := strings.TrimSpace(text)
+		isComment := strings.HasPrefix(trimmedLine, "//") || strings.HasPrefix(trimmedLine, "/*")
+
+		var status string
+		if len(findAllInLine(text, p.Text, p.re)) > 0 {
+			status = "match"
+			if isComment {
+				status = "rejected, in a comment"
+			}
+		} else if reason := nearMatch(text, p.Text, p.re); reason != "" {
+			status = "near match, " + reason
+		}
+
+		if status != "" {
+			found = true
+			fmt.Fprintf(w, "    line %d: %s: %s\n", line, status, truncateLine(trimmedLine))
+		}
+		line++
+	}
+
+	if !found {
+		fmt.Fprintln(w, "    no matches or near matches")
+	}
+}
+
+// nearMatch describes how a line that does not match a code block comes
+// close to it, or returns "" when it does not
+func nearMatch(line string, text string, re *regexp.Regexp) string {
+	if re != nil {
+		if folded, err := regexp.Compile("(?i)" + re.String()); err == nil && folded.MatchString(line) {
+			return "differs only in case"
+		}
+		return ""
+	}
+
+	if strings.Contains(strings.ToLower(line), strings.ToLower(text)) {
+		return "differs only in case"
+	}
+	if compact := strings.Join(strings.Fields(text), ""); compact != "" && strings.Contains(strings.Join(strings.Fields(line), ""), compact) {
+		return "differs only in whitespace"
+	}
+
+	// Lines sharing most of the code block's words, e.g. a renamed variable
+	words := wordPattern.FindAllString(text, -1)
+	if len(words) < 2 {
+		return ""
+	}
+	lineWords := make(map[string]bool)
+	for _, word := range wordPattern.FindAllString(line, -1) {
+		lineWords[word] = true
+	}
+	var shared []string
+	for _, word := range words {
+		if lineWords[word] {
+			shared = append(shared, word)
+		}
+	}
+	if len(shared)*2 >= len(words) {
+		return fmt.Sprintf("shares %d of %d words", len(shared), len(words))
+	}
+	return ""
+}
+
+// truncateLine shortens a line for display
+func truncateLine(line string) string {
+	if len(line) <= maxExplainLineLength {
+		return line
+	}
+	return line[:maxExplainLineLength-3] + "..."
+}
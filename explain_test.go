@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExplainFunction(t *testing.T) {
+	tempDir := t.TempDir()
+	writeFiles(t, tempDir, map[string]string{
+		"user.ts": `export function getUser(id: string) {
+    // using ctx = getContext()
+    using context = getContext();
+    return USING CTX = getContext();
+}
+
+// @ts-analyzer-ignore
+export function skipped() {}
+
+export function ok() {
+    const inner = () => 1;
+    using ctx = getContext();
+}
+`,
+	})
+	filename := filepath.Join(tempDir, "user.ts")
+
+	rule, err := compileCodeBlockRule("using ctx = getContext()", false, false, "exported")
+	if err != nil {
+		t.Fatalf("Failed to compile rule: %v", err)
+	}
+	internal, err := compileCodeBlockRule("using ctx = getContext()", false, false, "internal")
+	if err != nil {
+		t.Fatalf("Failed to compile rule: %v", err)
+	}
+	rules := []*Rule{rule, internal}
+
+	tests := []struct {
+		name     string
+		line     int
+		passed   bool
+		expected []string
+	}{
+		{
+			name:   "failing function",
+			line:   3,
+			passed: false,
+			expected: []string{
+				"Function getUser (" + filename + ":1-5)",
+				"Selected by the queries for: exported",
+				"Ignore directive: none",
+				"line 2: rejected, in a comment: // using ctx = getContext()",
+				"line 3: near match, shares 2 of 3 words: using context = getContext();",
+				"line 4: near match, differs only in case: return USING CTX = getContext();",
+				"Result: fail at line 1: Missing required code block",
+				"Not applied: the rule checks internal functions",
+			},
+		},
+		{
+			name:     "ignored function",
+			line:     8,
+			passed:   true,
+			expected: []string{"Ignore directive: // @ts-analyzer-ignore on line 7, no rule is applied"},
+		},
+		{
+			name:     "passing function",
+			line:     12,
+			passed:   true,
+			expected: []string{"Function ok (", "line 12: match: using ctx = getContext();", "Result: pass"},
+		},
+		{
+			name:     "innermost function",
+			line:     11,
+			passed:   false,
+			expected: []string{"Function inner (", "Selected by the queries for: internal", "Not applied: the rule checks exported functions", "Applied as: internal"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var output bytes.Buffer
+			passed, err := explainFunction(&output, filename, tt.line, rules)
+			if err != nil {
+				t.Fatalf("Failed to explain: %v", err)
+			}
+			if passed != tt.passed {
+				t.Errorf("Expected passed=%v, got %v", tt.passed, passed)
+			}
+			for _, expected := range tt.expected {
+				if !strings.Contains(output.String(), expected) {
+					t.Errorf("Expected output to contain %q, got:\n%s", expected, output.String())
+				}
+			}
+		})
+	}
+
+	if _, err := explainFunction(&bytes.Buffer{}, filename, 6, rules); err == nil {
+		t.Error("Expected an error for a line outside every function")
+	}
+}
+
+func TestParseExplainTarget(t *testing.T) {
+	if file, line, err := parseExplainTarget("src/a.ts:12"); err != nil || file != "src/a.ts" || line != 12 {
+		t.Errorf("Expected src/a.ts and 12, got %q, %d, %v", file, line, err)
+	}
+	for _, target := range []string{"src/a.ts", ":12", "src/a.ts:0", "src/a.ts:x"} {
+		if _, _, err := parseExplainTarget(target); err == nil {
+			t.Errorf("Expected an error for %q", target)
+		}
+	}
+}
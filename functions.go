@@ -0,0 +1,232 @@
+package main
+
+import (
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// FunctionFacts describes a selected function. It is the `fn` object that
+// CEL conditions are evaluated against.
+type FunctionFacts struct {
+	Name       string   `cel:"name"`
+	Kind       string   `cel:"kind"`
+	Exported   bool     `cel:"exported"`
+	Async      bool     `cel:"async"`
+	File       string   `cel:"file"`
+	Line       int      `cel:"line"`
+	EndLine    int      `cel:"end_line"`
+	Class      string   `cel:"class"`
+	Decorators []string `cel:"decorators"`
+	ParamTypes []string `cel:"param_types"`
+	ReturnType string   `cel:"return_type"`
+	Text       string   `cel:"text"`
+}
+
+// newFunctionFacts collects the facts about a function node of the given type
+func newFunctionFacts(funcNode *sitter.Node, rootNode *sitter.Node, content []byte, fnType string, filename string) *FunctionFacts {
+	return &FunctionFacts{
+		Name:       functionName(funcNode, content),
+		Kind:       fnType,
+		Exported:   isExportedFunction(funcNode, rootNode),
+		Async:      isAsyncFunction(funcNode),
+		File:       filename,
+		Line:       int(funcNode.StartPoint().Row) + 1,
+		EndLine:    int(funcNode.EndPoint().Row) + 1,
+		Class:      className(funcNode, content),
+		Decorators: functionDecorators(funcNode, content),
+		ParamTypes: functionParamTypes(funcNode, content),
+		ReturnType: functionReturnType(funcNode, content),
+		Text:       nodeText(funcNode, content),
+	}
+}
+
+// nodeText returns the source text of a node
+func nodeText(node *sitter.Node, content []byte) string {
+	return string(content[node.StartByte():node.EndByte()])
+}
+
+// functionNode returns the function itself when the query captured the
+// declaration that holds it (e.g. `const f = function() {}`)
+func functionNode(node *sitter.Node) *sitter.Node {
+	if node.Type() != "lexical_declaration" && node.Type() != "variable_declaration" {
+		return node
+	}
+
+	for i := 0; i < int(node.NamedChildCount()); i++ {
+		declarator := node.NamedChild(i)
+		if declarator.Type() != "variable_declarator" {
+			continue
+		}
+		if value := declarator.ChildByFieldName("value"); value != nil {
+			return value
+		}
+	}
+
+	return node
+}
+
+// functionName returns the name a function is known by, or "" for anonymous functions
+func functionName(funcNode *sitter.Node, content []byte) string {
+	funcNode = functionNode(funcNode)
+
+	if name := funcNode.ChildByFieldName("name"); name != nil {
+		return nodeText(name, content)
+	}
+
+	// Anonymous functions take the name of what they are assigned to
+	parent := funcNode.Parent()
+	if parent == nil {
+		return ""
+	}
+
+	switch parent.Type() {
+	case "variable_declarator", "public_field_definition":
+		if name := parent.ChildByFieldName("name"); name != nil {
+			return nodeText(name, content)
+		}
+	case "pair":
+		if key := parent.ChildByFieldName("key"); key != nil {
+			return nodeText(key, content)
+		}
+	case "assignment_expression":
+		if left := parent.ChildByFieldName("left"); left != nil {
+			return nodeText(left, content)
+		}
+	}
+
+	return ""
+}
+
+// isAsyncFunction reports whether the function is declared with the async keyword
+func isAsyncFunction(funcNode *sitter.Node) bool {
+	funcNode = functionNode(funcNode)
+
+	for i := 0; i < int(funcNode.ChildCount()); i++ {
+		child := funcNode.Child(i)
+		if child.Type() == "async" {
+			return true
+		}
+		if child.Type() == "formal_parameters" || child.Type() == "statement_block" {
+			break
+		}
+	}
+
+	return false
+}
+
+// enclosingClass returns the class declaration a method belongs to, if any
+func enclosingClass(funcNode *sitter.Node) *sitter.Node {
+	for parent := funcNode.Parent(); parent != nil; parent = parent.Parent() {
+		switch parent.Type() {
+		case "class_declaration", "abstract_class_declaration", "class":
+			return parent
+		case "function_declaration", "function_expression", "arrow_function":
+			return nil
+		}
+	}
+	return nil
+}
+
+// className returns the name of the class a method belongs to, or ""
+func className(funcNode *sitter.Node, content []byte) string {
+	class := enclosingClass(funcNode)
+	if class == nil {
+		return ""
+	}
+	if name := class.ChildByFieldName("name"); name != nil {
+		return nodeText(name, content)
+	}
+	return ""
+}
+
+// functionDecorators returns the names of the decorators applied to a method
+// and to the class it belongs to, without the leading @ or arguments
+func functionDecorators(funcNode *sitter.Node, content []byte) []string {
+	var decorators []string
+
+	// Method decorators are the siblings right before the method in the class body
+	for sibling := funcNode.PrevNamedSibling(); sibling != nil && sibling.Type() == "decorator"; sibling = sibling.PrevNamedSibling() {
+		decorators = append(decorators, decoratorName(sibling, content))
+	}
+
+	class := enclosingClass(funcNode)
+	if class == nil {
+		return decorators
+	}
+
+	// Decorators on an exported class belong to the export statement
+	holders := []*sitter.Node{class}
+	if parent := class.Parent(); parent != nil && parent.Type() == "export_statement" {
+		holders = append(holders, parent)
+	}
+
+	for _, holder := range holders {
+		for i := 0; i < int(holder.NamedChildCount()); i++ {
+			if child := holder.NamedChild(i); child.Type() == "decorator" {
+				decorators = append(decorators, decoratorName(child, content))
+			}
+		}
+	}
+
+	return decorators
+}
+
+// decoratorName returns the name of a decorator, e.g. "Get" for @Get('/users')
+func decoratorName(decorator *sitter.Node, content []byte) string {
+	if decorator.NamedChildCount() == 0 {
+		return normalizeDecorator(nodeText(decorator, content))
+	}
+
+	expression := decorator.NamedChild(0)
+	if expression.Type() == "call_expression" {
+		if function := expression.ChildByFieldName("function"); function != nil {
+			expression = function
+		}
+	}
+
+	return nodeText(expression, content)
+}
+
+// normalizeDecorator strips the leading @ and any arguments from a decorator
+func normalizeDecorator(decorator string) string {
+	decorator = strings.TrimPrefix(strings.TrimSpace(decorator), "@")
+	if index := strings.Index(decorator, "("); index >= 0 {
+		decorator = decorator[:index]
+	}
+	return strings.TrimSpace(decorator)
+}
+
+// functionParamTypes returns the type annotation of each typed parameter
+func functionParamTypes(funcNode *sitter.Node, content []byte) []string {
+	funcNode = functionNode(funcNode)
+
+	params := funcNode.ChildByFieldName("parameters")
+	if params == nil {
+		return nil
+	}
+
+	var types []string
+	for i := 0; i < int(params.NamedChildCount()); i++ {
+		if annotation := params.NamedChild(i).ChildByFieldName("type"); annotation != nil {
+			types = append(types, typeAnnotationText(annotation, content))
+		}
+	}
+
+	return types
+}
+
+// functionReturnType returns the declared return type of a function, or ""
+func functionReturnType(funcNode *sitter.Node, content []byte) string {
+	funcNode = functionNode(funcNode)
+
+	if annotation := funcNode.ChildByFieldName("return_type"); annotation != nil {
+		return typeAnnotationText(annotation, content)
+	}
+	return ""
+}
+
+// typeAnnotationText returns the type from an annotation without the leading colon
+func typeAnnotationText(annotation *sitter.Node, content []byte) string {
+	return strings.TrimSpace(strings.TrimPrefix(nodeText(annotation, content), ":"))
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFunctionFilter(t *testing.T) {
+	rootNode, content := parseSource(t, `
+@Resolver()
+export class UserResolver {
+    @Get('/users')
+    async list(ctx: Context): Promise<User[]> {
+        return [];
+    }
+
+    helper(id: string): number {
+        return 1;
+    }
+}
+
+export async function handleRequest(ctx: Context, id?: string) {
+    return id;
+}
+
+export const handleEvent = (event: Event): void => {};
+
+export function other() {}
+`)
+
+	testCases := []struct {
+		name     string
+		filter   FunctionFilter
+		expected []string
+	}{
+		{
+			name:     "No filter",
+			expected: []string{"list", "helper", "handleRequest", "handleEvent", "other"},
+		},
+		{
+			name:     "Name pattern",
+			filter:   FunctionFilter{NamePattern: "^handle"},
+			expected: []string{"handleRequest", "handleEvent"},
+		},
+		{
+			name:     "Method decorator",
+			filter:   FunctionFilter{Decorator: "@Get()"},
+			expected: []string{"list"},
+		},
+		{
+			name:     "Class decorator",
+			filter:   FunctionFilter{Decorator: "Resolver"},
+			expected: []string{"list", "helper"},
+		},
+		{
+			name:     "Async only",
+			filter:   FunctionFilter{Async: true},
+			expected: []string{"list", "handleRequest"},
+		},
+		{
+			name:     "Parameter type",
+			filter:   FunctionFilter{ParamType: "Context"},
+			expected: []string{"list", "handleRequest"},
+		},
+		{
+			name:     "Return type",
+			filter:   FunctionFilter{ReturnType: "Promise<.*>"},
+			expected: []string{"list"},
+		},
+		{
+			name:     "Combined filters",
+			filter:   FunctionFilter{Async: true, NamePattern: "^handle", ParamType: "Context"},
+			expected: []string{"handleRequest"},
+		},
+		{
+			name:     "CEL condition",
+			filter:   FunctionFilter{When: `fn.exported && fn.async && fn.name.startsWith("handle") && !fn.file.contains("/test/")`},
+			expected: []string{"handleRequest"},
+		},
+		{
+			name:     "CEL condition on class and decorators",
+			filter:   FunctionFilter{When: `fn.class == "UserResolver" && "Get" in fn.decorators && fn.line == 5`},
+			expected: []string{"list"},
+		},
+	}
+
+	functions, err := selectFunctions(rootNode, "all")
+	if err != nil {
+		t.Fatalf("Error selecting functions: %v", err)
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			filter := tc.filter
+			if err := filter.validate(); err != nil {
+				t.Fatalf("Unexpected validation error: %v", err)
+			}
+
+			var names []string
+			for _, funcNode := range functions {
+				selected, err := filter.selects(funcNode, rootNode, content, "all", "/src/handlers.ts")
+				if err != nil {
+					t.Fatalf("Unexpected error selecting functions: %v", err)
+				}
+				if selected {
+					names = append(names, functionName(funcNode, content))
+				}
+			}
+
+			if !reflect.DeepEqual(names, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, names)
+			}
+		})
+	}
+}
+
+func TestCompileCondition(t *testing.T) {
+	testCases := []struct {
+		expression string
+		wantErr    bool
+	}{
+		{expression: `fn.exported && fn.name.startsWith("handle")`},
+		{expression: `size(fn.param_types) > 1 || fn.return_type.matches("^Promise")`},
+		{expression: `fn.name`, wantErr: true},
+		{expression: `fn.unknown_field`, wantErr: true},
+		{expression: `fn.exported &&`, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		_, err := compileCondition(tc.expression)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("compileCondition(%q) error = %v, wantErr %v", tc.expression, err, tc.wantErr)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/typescript/typescript"
+)
+
+// Kind of the functions that only the query for every function finds
+const otherFnKind = "other"
+
+// Function types in the order their kinds are listed
+var inventoryFnTypes = []string{"exported", "internal", "callback", publicAPIFnType}
+
+// functionInfo is a function as the analyzer classifies it
+type functionInfo struct {
+	File     string   `json:"file"`
+	Line     int      `json:"line"`
+	EndLine  int      `json:"end_line"`
+	Name     string   `json:"name"`
+	Kinds    []string `json:"kinds"`
+	Exported bool     `json:"exported"` // What isExportedFunction decides
+	Async    bool     `json:"async"`
+	Class    string   `json:"class,omitempty"`
+	Ignored  bool     `json:"ignored"`
+
+	node  *sitter.Node
+	start uint32
+}
+
+// runListFunctions implements the list-functions subcommand and returns the exit code
+func runListFunctions(args []string) int {
+	flags := flag.NewFlagSet("list-functions", flag.ContinueOnError)
+	var (
+		fileGlob   string
+		directory  string
+		fnTypes    string
+		project    string
+		jsonOutput bool
+	)
+	flags.StringVar(&fileGlob, "file-glob", "**/*.ts", "File glob pattern to search")
+	flags.StringVar(&directory, "dir", ".", "Directory to search in")
+	flags.StringVar(&fnTypes, "fn-types", "", "Only list functions of these types: 'exported', 'internal', 'callback', 'public-api', or comma-separated combination")
+	flags.StringVar(&project, "project", "", "Path to a tsconfig.json (or its directory) whose files are listed instead of -file-glob")
+	flags.BoolVar(&jsonOutput, "json", false, "Print the functions as JSON")
+	if err := flags.Parse(args); err != nil {
+		return exitUsageError
+	}
+
+	var only map[string]bool
+	if fnTypes != "" {
+		if only = parseFunctionTypes(fnTypes); len(only) == 0 {
+			fmt.Fprintln(os.Stderr, "Error: Invalid function types. Use 'exported', 'internal', 'callback', 'public-api', or a comma-separated combination")
+			return exitUsageError
+		}
+	}
+
+	if project != "" {
+		var err error
+		activeProject, err = loadProject(project)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading project: %v\n", err)
+			return exitUsageError
+		}
+	}
+
+	if directory != "." {
+		if err := os.Chdir(directory); err != nil {
+			fmt.Fprintf(os.Stderr, "Error changing to directory %s: %v\n", directory, err)
+			return exitUsageError
+		}
+	}
+
+	var files []string
+	if activeProject != nil {
+		files = activeProject.allFiles()
+	} else {
+		var err error
+		if files, err = findFiles(fileGlob); err != nil {
+			fmt.Fprintf(os.Stderr, "Error finding files: %v\n", err)
+			return exitUsageError
+		}
+	}
+
+	parser := sitter.NewParser()
+	parser.SetLanguage(typescript.GetLanguage())
+
+	functions := []functionInfo{}
+	for _, file := range files {
+		if strings.Contains(file, "node_modules") || !(strings.HasSuffix(file, ".ts") || strings.HasSuffix(file, ".tsx")) {
+			continue
+		}
+
+		absPath, err := filepath.Abs(file)
+		if err != nil {
+			absPath = file
+		}
+		content, err := os.ReadFile(absPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading file %s: %v\n", absPath, err)
+			return exitAnalysisError
+		}
+
+		tree := parser.Parse(nil, content)
+		found, err := listFunctions(tree.RootNode(), content, absPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitAnalysisError
+		}
+
+		for _, fn := range found {
+			if only == nil || hasAnyKind(fn.Kinds, only) {
+				functions = append(functions, fn)
+			}
+		}
+	}
+
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(functions); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing functions: %v\n", err)
+			return exitAnalysisError
+		}
+		return exitClean
+	}
+
+	printFunctionTable(os.Stdout, functions)
+	return exitClean
+}
+
+// listFunctions returns every function the function queries find in a file,
+// in source order, with the types whose query selects it
+func listFunctions(rootNode *sitter.Node, content []byte, filename string) ([]functionInfo, error) {
+	kinds := make(map[uint32][]string)
+	nodes := make(map[uint32]*sitter.Node)
+
+	add := func(funcNode *sitter.Node, kind string) {
+		funcNode = functionNode(funcNode)
+		start := funcNode.StartByte()
+		if _, ok := nodes[start]; !ok {
+			nodes[start] = funcNode
+		}
+		kinds[start] = append(kinds[start], kind)
+	}
+
+	for _, fnType := range inventoryFnTypes {
+		var found []*sitter.Node
+		if fnType == publicAPIFnType {
+			found = selectPublicFunctions(rootNode, content, filename)
+		} else {
+			var err error
+			if found, err = selectFunctions(rootNode, fnType); err != nil {
+				return nil, fmt.Errorf("creating query for file %s: %w", filename, err)
+			}
+		}
+		for _, funcNode := range found {
+			add(funcNode, fnType)
+		}
+	}
+
+	others, err := selectFunctions(rootNode, "all")
+	if err != nil {
+		return nil, fmt.Errorf("creating query for file %s: %w", filename, err)
+	}
+	for _, funcNode := range others {
+		if _, ok := nodes[functionNode(funcNode).StartByte()]; !ok {
+			add(funcNode, otherFnKind)
+		}
+	}
+
+	lines := newLineIndex(content)
+
+	functions := make([]functionInfo, 0, len(nodes))
+	for start, funcNode := range nodes {
+		functions = append(functions, functionInfo{
+			File:     filename,
+			Line:     int(funcNode.StartPoint().Row) + 1,
+			EndLine:  int(funcNode.EndPoint().Row) + 1,
+			Name:     functionName(funcNode, content),
+			Kinds:    kinds[start],
+			Exported: isExportedFunction(funcNode, rootNode),
+			Async:    isAsyncFunction(funcNode),
+			Class:    className(funcNode, content),
+			Ignored:  hasIgnoreComment(content, lines, funcNode),
+			node:     funcNode,
+			start:    start,
+		})
+	}
+
+	sort.Slice(functions, func(i, j int) bool {
+		return functions[i].start < functions[j].start
+	})
+	return functions, nil
+}
+
+// hasAnyKind reports whether any of the kinds is selected
+func hasAnyKind(kinds []string, selected map[string]bool) bool {
+	for _, kind := range kinds {
+		if selected[kind] {
+			return true
+		}
+	}
+	return false
+}
+
+// printFunctionTable prints the functions as an aligned table
+func printFunctionTable(w io.Writer, functions []functionInfo) {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "FILE\tLINES\tNAME\tKIND\tEXPORTED\tASYNC\tCLASS\tIGNORED")
+
+	for _, fn := range functions {
+		name := fn.Name
+		if name == "" {
+			name = "(anonymous)"
+		}
+		class := fn.Class
+		if class == "" {
+			class = "-"
+		}
+		fmt.Fprintf(table, "%s\t%d-%d\t%s\t%s\t%t\t%t\t%s\t%t\n",
+			fn.File, fn.Line, fn.EndLine, name, strings.Join(fn.Kinds, ","), fn.Exported, fn.Async, class, fn.Ignored)
+	}
+
+	table.Flush()
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestListFunctions(t *testing.T) {
+	rootNode, content := parseSource(t, `export async function load() {
+    return [1].map((x) => x * 2);
+}
+
+// @ts-analyzer-ignore
+const helper = function () {};
+
+export const handler = async () => {};
+
+class Service {
+    run() {}
+}
+
+const obj = { fn: () => 1 };
+`)
+
+	functions, err := listFunctions(rootNode, content, "/tmp/inventory/a.ts")
+	if err != nil {
+		t.Fatalf("Failed to list functions: %v", err)
+	}
+
+	type summary struct {
+		Name     string
+		Line     int
+		Kinds    string
+		Exported bool
+		Async    bool
+		Class    string
+		Ignored  bool
+	}
+	var got []summary
+	for _, fn := range functions {
+		got = append(got, summary{fn.Name, fn.Line, strings.Join(fn.Kinds, ","), fn.Exported, fn.Async, fn.Class, fn.Ignored})
+	}
+
+	expected := []summary{
+		{"load", 1, "exported", true, true, "", false},
+		{"", 2, "callback", false, false, "", false},
+		{"helper", 6, "internal", false, false, "", true},
+		{"handler", 8, "exported", true, true, "", false},
+		{"run", 11, "internal", false, false, "Service", false},
+		{"fn", 14, "other", false, false, "", false},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected functions %+v, got %+v", expected, got)
+	}
+
+	var table bytes.Buffer
+	printFunctionTable(&table, functions[:2])
+	lines := strings.Split(strings.TrimSpace(table.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "FILE") {
+		t.Fatalf("Expected a header and two rows, got:\n%s", table.String())
+	}
+	if fields := strings.Fields(lines[2]); !reflect.DeepEqual(fields, []string{"/tmp/inventory/a.ts", "2-2", "(anonymous)", "callback", "false", "false", "-", "false"}) {
+		t.Errorf("Unexpected row %q", lines[2])
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import "bytes"
+
+// lineIndex holds the byte offset where each line of a file starts, so any
+// line can be looked up without rescanning the file
+type lineIndex []uint32
+
+// newLineIndex indexes the lines of a file in a single pass
+func newLineIndex(content []byte) lineIndex {
+	index := lineIndex{0}
+	for offset := 0; ; {
+		next := bytes.IndexByte(content[offset:], '\n')
+		if next < 0 {
+			break
+		}
+		offset += next + 1
+		index = append(index, uint32(offset))
+	}
+	return index
+}
+
+// line returns the content of a zero-based line without its newline, or nil
+// if the file has no such line
+func (index lineIndex) line(content []byte, row uint32) []byte {
+	if int(row) >= len(index) {
+		return nil
+	}
+
+	start := index[row]
+	end := uint32(len(content))
+	if int(row)+1 < len(index) {
+		end = index[row+1] - 1
+	}
+	return content[start:end]
+}
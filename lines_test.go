@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLineIndex(t *testing.T) {
+	content := []byte("first\n\nthird\r\nlast")
+	lines := newLineIndex(content)
+
+	expected := []string{"first", "", "third\r", "last"}
+	for row, want := range expected {
+		if got := string(lines.line(content, uint32(row))); got != want {
+			t.Errorf("line(%d) = %q, want %q", row, got, want)
+		}
+	}
+
+	if got := lines.line(content, uint32(len(expected))); got != nil {
+		t.Errorf("Expected nil past the last line, got %q", got)
+	}
+
+	trailing := []byte("only\n")
+	if got := string(newLineIndex(trailing).line(trailing, 1)); got != "" {
+		t.Errorf("Expected an empty line after a trailing newline, got %q", got)
+	}
+}
+
+// TestLineIndexMatchesNaiveCount looks up the line of many offsets of a large
+// file and compares it with the line found by scanning the file from the start
+func TestLineIndexMatchesNaiveCount(t *testing.T) {
+	content := generatedSource(20000)
+	lines := newLineIndex(content)
+
+	if want := bytes.Count(content, []byte("\n")) + 1; len(lines) != want {
+		t.Fatalf("Expected %d lines, got %d", want, len(lines))
+	}
+
+	// The naive count continues from the previous offset to keep the test fast
+	row, counted := 0, 0
+	for offset := 0; offset < len(content); offset += 97 {
+		row += bytes.Count(content[counted:offset], []byte("\n"))
+		counted = offset
+		start := bytes.LastIndexByte(content[:offset], '\n') + 1
+		end := len(content)
+		if next := bytes.IndexByte(content[offset:], '\n'); next >= 0 {
+			end = offset + next
+		}
+
+		if got := lines.line(content, uint32(row)); !bytes.Equal(got, content[start:end]) {
+			t.Fatalf("Offset %d: expected line %d to be %q, got %q", offset, row, content[start:end], got)
+		}
+	}
+}
+
+// generatedSource builds a file shaped like a generated SDK, with every other
+// function ignored
+func generatedSource(functions int) []byte {
+	var b strings.Builder
+	for i := 0; i < functions; i++ {
+		if i%2 == 0 {
+			b.WriteString("// @ts-analyzer-ignore\n")
+		}
+		fmt.Fprintf(&b, "export function op%d(input: Input) {\n    using ctx = getContext();\n    return client.call(%d, input);\n}\n\n", i, i)
+	}
+	return []byte(b.String())
+}
+
+// BenchmarkCheckExportedFunctions checks files of growing size. The time per
+// function should stay flat as the file grows.
+func BenchmarkCheckExportedFunctions(b *testing.B) {
+	for _, functions := range []int{500, 1000, 2000, 4000, 8000} {
+		b.Run(fmt.Sprintf("functions=%d", functions), func(b *testing.B) {
+			rootNode, content := parseSource(b, string(generatedSource(functions)))
+			rule, err := compileCodeBlockRule("getContext()", false, false, "exported")
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				checkFunctions(rootNode, content, "exported", []*Rule{rule}, "generated.ts")
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*functions), "ns/function")
+		})
+	}
+}
+
+// BenchmarkHasIgnoreComment measures the lookup alone, including building the
+// index once per file
+func BenchmarkHasIgnoreComment(b *testing.B) {
+	for _, functions := range []int{1000, 4000, 16000} {
+		b.Run(fmt.Sprintf("functions=%d", functions), func(b *testing.B) {
+			rootNode, content := parseSource(b, string(generatedSource(functions)))
+			funcNodes, err := selectFunctions(rootNode, "exported")
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				lines := newLineIndex(content)
+				for _, funcNode := range funcNodes {
+					hasIgnoreComment(content, lines, funcNode)
+				}
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*functions), "ns/function")
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// logger receives everything the analyzer reports besides findings and
+// summaries. It writes to stderr so stdout stays parseable, and shows only
+// warnings until main configures it.
+var logger = newLogger(os.Stderr, slog.LevelWarn, "text")
+
+// parseLogLevel parses the value of -log-level
+func parseLogLevel(name string) (slog.Level, error) {
+	switch name {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	}
+	return 0, fmt.Errorf("invalid log level %q, use 'debug', 'info' or 'warn'", name)
+}
+
+// newLogger creates a logger writing records at or above level to w, as
+// logfmt-style text or as one JSON object per line
+func newLogger(w io.Writer, level slog.Level, format string) *slog.Logger {
+	options := &slog.HandlerOptions{Level: level}
+	if format == "json" {
+		return slog.New(slog.NewJSONHandler(w, options))
+	}
+	return slog.New(slog.NewTextHandler(w, options))
+}
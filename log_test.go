@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	for name, expected := range map[string]slog.Level{"debug": slog.LevelDebug, "info": slog.LevelInfo, "warn": slog.LevelWarn} {
+		if level, err := parseLogLevel(name); err != nil || level != expected {
+			t.Errorf("Expected %s to parse as %v, got %v, %v", name, expected, level, err)
+		}
+	}
+	if _, err := parseLogLevel("trace"); err == nil {
+		t.Error("Expected an error for an unknown level")
+	}
+}
+
+func TestLogger(t *testing.T) {
+	original := logger
+	defer func() { logger = original }()
+
+	// Debugging details are only written at the debug level
+	var output bytes.Buffer
+	logger = newLogger(&output, slog.LevelInfo, "text")
+	isCodeBlockUsedInFunction("function f() {\n    requiredCode();\n}", "requiredCode()", false)
+	if output.Len() != 0 {
+		t.Errorf("Expected no output at the info level, got %q", output.String())
+	}
+
+	logger = newLogger(&output, slog.LevelDebug, "text")
+	isCodeBlockUsedInFunction("function f() {\n    requiredCode();\n}", "requiredCode()", false)
+	if !strings.Contains(output.String(), `level=DEBUG msg="found code block in non-comment line" line=requiredCode();`) {
+		t.Errorf("Expected a debug record for the match, got %q", output.String())
+	}
+
+	// JSON logs have one object per line
+	output.Reset()
+	logger = newLogger(&output, slog.LevelWarn, "json")
+	logger.Debug("hidden")
+	logger.Warn("writing cache", "file", "a.ts")
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("Expected one record, got %q", output.String())
+	}
+	var record map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("Expected a JSON record: %v", err)
+	}
+	if record["level"] != "WARN" || record["msg"] != "writing cache" || record["file"] != "a.ts" {
+		t.Errorf("Unexpected record %v", record)
+	}
+}
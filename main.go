@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
 	"os"
@@ -8,6 +9,7 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
 
 	sitter "github.com/smacker/go-tree-sitter"
 	"github.com/smacker/go-tree-sitter/typescript/typescript"
@@ -17,16 +19,56 @@ import (
 // For testing purposes
 var osExit = os.Exit
 
+// Exit codes of a run
+const (
+	exitClean         = 0 // Every file passed
+	exitViolations    = 1 // Some rule found issues
+	exitUsageError    = 2 // Invalid flags, configuration, project or workspace
+	exitAnalysisError = 3 // Some file could not be read or analyzed
+)
+
 func main() {
+	// Subcommands come before any flag
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "list-functions":
+			osExit(runListFunctions(os.Args[2:]))
+			return
+		case "validate-config":
+			osExit(runValidateConfig(os.Args[2:]))
+			return
+		case "schema":
+			osExit(runSchema(os.Args[2:]))
+			return
+		}
+	}
+
 	// Parse command line arguments
 	var (
-		codeBlock string
-		isRegex   bool
-		invert    bool
-		fileGlob  string
-		directory string
-		fnTypes   string
-		verbose   bool
+		codeBlock  string
+		isRegex    bool
+		invert     bool
+		fileGlob   string
+		directory  string
+		fnTypes    string
+		verbose    bool
+		logLevel   string
+		logFormat  string
+		config     string
+		cacheDir   string
+		project    string
+		workspaces bool
+		jsonOutput bool
+		cycles     bool
+		unused     bool
+		explain    string
+		severity   string
+		maxWarning int
+		minCount   int
+		maxCount   int
+		transitive bool
+		depth      int
+		filter     FunctionFilter
 	)
 
 	flag.StringVar(&codeBlock, "code-block", "", "Code block to check for")
@@ -34,51 +76,359 @@ func main() {
 	flag.BoolVar(&invert, "invert", false, "Invert the check (find functions that DO have the code block)")
 	flag.StringVar(&fileGlob, "file-glob", "**/*.ts", "File glob pattern to search")
 	flag.StringVar(&directory, "dir", ".", "Directory to search in")
-	flag.StringVar(&fnTypes, "fn-types", "exported", "Function types to check: 'exported', 'internal', 'callback', or comma-separated combination")
-	flag.BoolVar(&verbose, "verbose", false, "Enable verbose output")
+	flag.StringVar(&fnTypes, "fn-types", "exported", "Function types to check: 'exported', 'internal', 'callback', 'public-api', or comma-separated combination")
+	flag.BoolVar(&verbose, "verbose", false, "Log debugging details, the same as -log-level=debug")
+	flag.StringVar(&logLevel, "log-level", "warn", "Lowest level of the messages logged to stderr: 'debug', 'info' or 'warn'")
+	flag.StringVar(&logFormat, "log-format", "text", "Format of the messages logged to stderr: 'text' or 'json'")
+	flag.IntVar(&minCount, "min", -1, "Minimum number of code block occurrences per function (-1 for no minimum)")
+	flag.IntVar(&maxCount, "max", -1, "Maximum number of code block occurrences per function (-1 for no maximum)")
+	flag.BoolVar(&transitive, "transitive", false, "Accept functions whose every chain of calls reaches the code block")
+	flag.IntVar(&depth, "transitive-depth", defaultTransitiveDepth, "Maximum number of calls followed by -transitive")
+	flag.StringVar(&filter.NamePattern, "fn-name", "", "Only check functions whose name matches this regular expression")
+	flag.StringVar(&filter.Decorator, "fn-decorator", "", "Only check methods with this decorator, on the method or its class (e.g. '@Get')")
+	flag.BoolVar(&filter.Async, "fn-async", false, "Only check async functions")
+	flag.StringVar(&filter.ParamType, "fn-param-type", "", "Only check functions with a parameter whose type matches this regular expression")
+	flag.StringVar(&filter.ReturnType, "fn-return-type", "", "Only check functions whose return type matches this regular expression")
+	flag.StringVar(&filter.When, "when", "", "Only check functions for which this CEL expression (over the fn object) is true")
+	flag.BoolVar(&cycles, "cycles", false, "Report import cycles between the checked files")
+	flag.BoolVar(&unused, "unused-exports", false, "Report exported functions and constants that no other checked file imports")
+	flag.StringVar(&severity, "severity", severityError, "Severity of the findings of -code-block, -cycles and -unused-exports: 'error', 'warning' or 'info'")
+	flag.IntVar(&maxWarning, "max-warnings", -1, "Fail the run when there are more warnings than this (-1 for no limit)")
+	flag.StringVar(&explain, "explain", "", "Explain how every rule treats the function at file:line instead of checking all files")
+	flag.StringVar(&config, "config", "", "Path to a YAML file with additional rules")
+	flag.StringVar(&project, "project", "", "Path to a tsconfig.json (or its directory) whose files are checked instead of -file-glob")
+	flag.BoolVar(&workspaces, "workspaces", false, "Discover pnpm or npm workspace packages and group the summary by package")
+	flag.BoolVar(&jsonOutput, "json", false, "Print the results as JSON")
+	flag.StringVar(&cacheDir, "cache-dir", "", "Directory to cache per-file results in between runs")
 	flag.Parse()
 
+	// Diagnostics go to stderr so stdout only carries the results
+	if verbose {
+		logLevel = "debug"
+	}
+	level, err := parseLogLevel(logLevel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		flag.Usage()
+		os.Exit(exitUsageError)
+	}
+	if logFormat != "text" && logFormat != "json" {
+		fmt.Fprintf(os.Stderr, "Error: invalid log format %q, use 'text' or 'json'\n", logFormat)
+		flag.Usage()
+		os.Exit(exitUsageError)
+	}
+	logger = newLogger(os.Stderr, level, logFormat)
+
 	// Validate function types
-	fnTypesMap := parseFunctionTypes(fnTypes)
-	if len(fnTypesMap) == 0 {
-		fmt.Println("Error: Invalid function types. Use 'exported', 'internal', 'callback', or a comma-separated combination")
+	if len(parseFunctionTypes(fnTypes)) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: Invalid function types. Use 'exported', 'internal', 'callback', 'public-api', or a comma-separated combination")
 		flag.Usage()
-		os.Exit(1)
+		os.Exit(exitUsageError)
+	}
+
+	// Compile every rule before any file is read, so bad patterns stop the run
+	var rules []*Rule
+	if codeBlock != "" {
+		rule := newCodeBlockRule(codeBlock, isRegex, invert, fnTypes)
+		rule.FunctionFilter = filter
+		if minCount >= 0 {
+			rule.Min = &minCount
+		}
+		if maxCount >= 0 {
+			rule.Max = &maxCount
+		}
+		rule.Transitive = transitive
+		rule.Depth = depth
+		rule.Severity = severity
+		if err := rule.validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			flag.Usage()
+			os.Exit(exitUsageError)
+		}
+		rules = append(rules, rule)
+	}
+
+	// Load rules from the configuration file before changing directory
+	var overrides []*Override
+	var loadedConfigs []string
+	if config != "" {
+		cfg, err := loadConfig(config)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+			os.Exit(exitUsageError)
+		}
+		rules = append(rules, cfg.Rules...)
+		overrides = cfg.Overrides
+		if absPath, err := filepath.Abs(config); err == nil {
+			loadedConfigs = append(loadedConfigs, absPath)
+		}
 	}
 
-	if codeBlock == "" {
-		fmt.Println("Error: code-block is required")
+	if cycles {
+		rules = append(rules, &Rule{Type: ruleTypeCycles, Severity: severity})
+	}
+	if unused {
+		rules = append(rules, &Rule{Type: ruleTypeUnused, Severity: severity})
+	}
+
+	if !isSeverity(severity) {
+		fmt.Fprintf(os.Stderr, "Error: invalid severity %q, use 'error', 'warning' or 'info'\n", severity)
 		flag.Usage()
-		os.Exit(1)
+		os.Exit(exitUsageError)
+	}
+
+	// Compile the function queries up front; rule patterns were compiled
+	// when the rules were validated
+	for fnType := range functionQueries {
+		if _, err := getFunctionQuery(fnType); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitAnalysisError)
+		}
+	}
+
+	// Open the cache before changing directory so a relative path means what the user typed
+	var cache *resultCache
+	if cacheDir != "" {
+		var err error
+		cache, err = newResultCache(cacheDir, rules)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening cache: %v\n", err)
+			os.Exit(exitAnalysisError)
+		}
+	}
+
+	// Load the project before changing directory, like the config file
+	if project != "" {
+		var err error
+		activeProject, err = loadProject(project)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading project: %v\n", err)
+			os.Exit(exitUsageError)
+		}
+		if cache != nil {
+			fingerprint, err := activeProject.fingerprint()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error loading project: %v\n", err)
+				os.Exit(exitUsageError)
+			}
+			cache.extendRunKey(fingerprint)
+		}
+	}
+
+	// The file to explain is relative to where the analyzer runs, or else to -dir
+	var explainFile string
+	var explainLine int
+	if explain != "" {
+		var err error
+		if explainFile, explainLine, err = parseExplainTarget(explain); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsageError)
+		}
+		if _, err := os.Stat(explainFile); err == nil {
+			explainFile, _ = filepath.Abs(explainFile)
+		}
 	}
 
 	// Change to the specified directory
 	if directory != "." {
 		err := os.Chdir(directory)
 		if err != nil {
-			fmt.Printf("Error changing to directory %s: %v\n", directory, err)
-			os.Exit(1)
+			fmt.Fprintf(os.Stderr, "Error changing to directory %s: %v\n", directory, err)
+			os.Exit(exitUsageError)
 		}
 	}
 
-	// Find all files matching the glob pattern
-	files, err := findFiles(fileGlob)
-	if err != nil {
-		fmt.Printf("Error finding files: %v\n", err)
-		os.Exit(1)
+	// Find all files matching the glob pattern, or the files of the project
+	var files []string
+	if activeProject != nil {
+		files = activeProject.allFiles()
+		if len(files) == 0 {
+			fmt.Fprintf(os.Stderr, "No files found in project: %s\n", activeProject.configPath)
+			os.Exit(exitUsageError)
+		}
+	} else {
+		var err error
+		files, err = findFiles(fileGlob)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error finding files: %v\n", err)
+			os.Exit(exitUsageError)
+		}
+
+		if len(files) == 0 {
+			fmt.Fprintf(os.Stderr, "No files found matching pattern: %s\n", fileGlob)
+			os.Exit(exitUsageError)
+		}
 	}
 
-	if len(files) == 0 {
-		fmt.Printf("No files found matching pattern: %s\n", fileGlob)
-		os.Exit(1)
+	logger.Info("found files to check", "files", len(files))
+
+	// Workspace packages group the results; their config files apply like
+	// those of any other directory
+	var packages []*workspacePackage
+	allRules := rules
+	if workspaces {
+		var err error
+		packages, err = findWorkspacePackages(".")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error finding workspace packages: %v\n", err)
+			os.Exit(exitUsageError)
+		}
+
+		// Files outside every package are grouped under the workspace root
+		root, _ := filepath.Abs(".")
+		packages = append(packages, &workspacePackage{Name: rootPackageName, Dir: root})
+
+		logger.Info("found workspace packages", "packages", len(packages)-1)
 	}
 
-	if verbose {
-		fmt.Printf("Found %d files to check\n", len(files))
+	// Rules over the whole tree see every checked source file
+	var sources []string
+	for _, file := range files {
+		if !strings.Contains(file, "node_modules") && isTypeScriptFile(file) {
+			sources = append(sources, file)
+		}
+	}
+
+	// Overrides and the config files of subdirectories give some files rules of their own
+	root, _ := filepath.Abs(".")
+	resolver := newRuleResolver(root, loadedConfigs)
+	overridden := make(map[string]*ruleSet)
+	resolve := func(absPath string) {
+		set, err := resolver.rulesFor(absPath, rules, overrides)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+			os.Exit(exitUsageError)
+		}
+		if set != nil {
+			overridden[absPath] = set
+		}
+	}
+	for _, file := range sources {
+		absPath, err := filepath.Abs(file)
+		if err != nil {
+			absPath = file
+		}
+		resolve(absPath)
+	}
+	if explain != "" {
+		explainFile, _ = filepath.Abs(explainFile)
+		resolve(explainFile)
+	}
+	for _, set := range resolver.ruleSets() {
+		allRules = append(allRules, set.rules...)
+	}
+
+	// Without -code-block or -config, the rules can all come from the config
+	// files of packages and other subdirectories
+	if len(allRules) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: code-block is required")
+		flag.Usage()
+		os.Exit(exitUsageError)
+	}
+
+	// Transitive rules follow calls into every file, not just the one being checked
+	if hasTransitiveRule(allRules) {
+		graph, err := buildCallGraph(sources)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error building call graph: %v\n", err)
+			os.Exit(exitAnalysisError)
+		}
+		for _, rule := range allRules {
+			rule.graph = graph
+		}
+		if cache != nil {
+			cache.extendRunKey(graph.fingerprint())
+		}
+	}
+
+	// Cycles are found in the imports between every checked file
+	if hasCyclesRule(allRules) {
+		modules, err := buildModuleGraph(sources)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error building module graph: %v\n", err)
+			os.Exit(exitAnalysisError)
+		}
+		for _, rule := range allRules {
+			rule.modules = modules
+		}
+		if cache != nil {
+			fingerprint, err := hashFiles(modules.files)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error opening cache: %v\n", err)
+				os.Exit(exitAnalysisError)
+			}
+			cache.extendRunKey(fingerprint)
+		}
+	}
+
+	// Import boundaries depend on which files the imports resolve to
+	if cache != nil && hasBoundaryRule(allRules) {
+		fingerprint, err := importResolutionFingerprint(sources)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening cache: %v\n", err)
+			os.Exit(exitAnalysisError)
+		}
+		cache.extendRunKey(fingerprint)
+	}
+
+	// An export is used when any other file imports it
+	if hasUnusedExportsRule(allRules) {
+		usage, err := buildExportUsage(sources)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error collecting imports: %v\n", err)
+			os.Exit(exitAnalysisError)
+		}
+		for _, rule := range allRules {
+			rule.usage = usage
+		}
+	}
+
+	// Whether a function is public depends on package.json and re-export chains,
+	// and whether an export is used on the imports of every file
+	if cache != nil && (hasPublicAPIRule(allRules) || hasUnusedExportsRule(allRules)) {
+		fingerprint, err := publicAPIFingerprint(files)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening cache: %v\n", err)
+			os.Exit(exitAnalysisError)
+		}
+		cache.extendRunKey(fingerprint)
+	}
+
+	if cache != nil {
+		for _, set := range resolver.ruleSets() {
+			var err error
+			if set.cache, err = cache.forRules(set.rules); err != nil {
+				fmt.Fprintf(os.Stderr, "Error opening cache: %v\n", err)
+				os.Exit(exitAnalysisError)
+			}
+		}
+	}
+
+	if explain != "" {
+		absPath := explainFile
+		fileRules := rules
+		if set := overridden[absPath]; set != nil {
+			fileRules = set.rules
+		}
+
+		passed, err := explainFunction(os.Stdout, absPath, explainLine, fileRules)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitAnalysisError)
+		}
+		if !passed {
+			osExit(exitViolations)
+		}
+		return
 	}
 
 	allFilesValid := true
 	invalidFiles := make(map[string]int) // Track files with issues and count of issues
+	var counts severityCounts            // Findings of every file by severity
+	fileFindings := make(map[string][]Finding)
+	analysisErrors := make(map[string]error)      // Files that could not be checked
+	syntaxErrors := make(map[string]*syntaxError) // Files checked despite syntax errors
+	filesChecked := 0
 
 	for _, file := range files {
 		// Skip node_modules
@@ -94,20 +444,64 @@ func main() {
 				absPath = file // Fallback to original path
 			}
 
-			if verbose {
-				fmt.Printf("Checking file: %s\n", absPath)
+			logger.Debug("checking file", "file", absPath)
+
+			fileRules, fileCache := rules, cache
+			pkg := packageFor(packages, absPath)
+			if pkg != nil {
+				pkg.files++
+			}
+			if set := overridden[absPath]; set != nil {
+				fileRules, fileCache = set.rules, set.cache
 			}
+			filesChecked++
 
-			valid, issueCount := processTypeScriptFile(file, codeBlock, isRegex, invert, fnTypesMap, verbose)
-			if !valid {
+			absPath, result, err := checkFile(file, fileRules, fileCache)
+			if err != nil {
+				logger.Error("analyzing file", "file", absPath, "error", err)
+				analysisErrors[absPath] = err
+				continue
+			}
+			if result.SyntaxError != nil {
+				syntaxErrors[absPath] = result.SyntaxError
+			}
+			findings := result.Findings
+			if !jsonOutput {
+				printFindings(absPath, findings)
+			}
+			if len(findings) > 0 {
 				allFilesValid = false
-				invalidFiles[absPath] = issueCount
+				invalidFiles[absPath] = len(findings)
+				fileFindings[absPath] = findings
+				counts.add(findings)
+				if pkg != nil {
+					pkg.issues += len(findings)
+					pkg.severities.add(findings)
+					pkg.filesWithIssues = append(pkg.filesWithIssues, absPath)
+				}
 			}
 		}
 	}
 
+	// Warnings and info are reported without failing the run, up to -max-warnings
+	failed := counts.failed(maxWarning)
+
+	if jsonOutput {
+		if err := printJSONReport(filesChecked, fileFindings, analysisErrors, syntaxErrors, packages, failed); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing report: %v\n", err)
+			os.Exit(exitAnalysisError)
+		}
+		if code := runExitCode(!failed, analysisErrors); code != exitClean {
+			osExit(code)
+		}
+		return
+	}
+
 	// Print summary
-	if !allFilesValid {
+	if !allFilesValid && packages != nil {
+		printWorkspaceSummary(packages, invalidFiles, rules)
+		fmt.Printf("\nTotal: %d file(s) with issues, %s\n", len(invalidFiles), counts)
+	} else if !allFilesValid {
 		fmt.Println("\nSummary of files with issues:")
 
 		// Get sorted list of filepaths
@@ -127,19 +521,71 @@ func main() {
 
 		// Print issues in sorted order
 		for _, absPath := range sortedPaths {
-			count := invalidFiles[absPath]
-			if invert {
-				fmt.Printf("%s: %d function(s) containing forbidden code block\n", absPath, count)
+			fmt.Printf("%s: %d %s\n", absPath, invalidFiles[absPath], issueLabel(rules))
+		}
+
+		fmt.Printf("\nTotal: %d file(s) with issues, %s\n", len(invalidFiles), counts)
+	}
+	if counts.Errors == 0 && maxWarning >= 0 && counts.Warnings > maxWarning {
+		fmt.Printf("\nToo many warnings: %d, at most %d allowed\n", counts.Warnings, maxWarning)
+	}
+
+	if len(syntaxErrors) > 0 {
+		fmt.Println("Warning: files with syntax errors, results may be incomplete:")
+		var sortedPaths []string
+		for path := range syntaxErrors {
+			sortedPaths = append(sortedPaths, path)
+		}
+		sort.Strings(sortedPaths)
+		for _, path := range sortedPaths {
+			if location := syntaxErrors[path]; location.Line > 0 {
+				fmt.Printf("%s:%d:%d\n", path, location.Line, location.Column)
 			} else {
-				fmt.Printf("%s: %d function(s) missing required code block\n", absPath, count)
+				fmt.Println(path)
 			}
 		}
+	}
+
+	if len(analysisErrors) > 0 {
+		fmt.Println("\nFiles that could not be analyzed:")
+		var sortedPaths []string
+		for path := range analysisErrors {
+			sortedPaths = append(sortedPaths, path)
+		}
+		sort.Strings(sortedPaths)
+		for _, path := range sortedPaths {
+			fmt.Printf("%s: %v\n", path, analysisErrors[path])
+		}
+	}
+
+	if code := runExitCode(!failed, analysisErrors); code != exitClean {
+		osExit(code) // Use the variable instead of direct call
+	} else if allFilesValid {
+		logger.Info("all functions contain the required code block", "files", filesChecked)
+	}
+}
+
+// runExitCode returns the exit code of a run. Analysis errors take precedence
+// over violations, since the results of the run are incomplete.
+func runExitCode(allFilesValid bool, analysisErrors map[string]error) int {
+	switch {
+	case len(analysisErrors) > 0:
+		return exitAnalysisError
+	case !allFilesValid:
+		return exitViolations
+	}
+	return exitClean
+}
 
-		fmt.Printf("\nTotal: %d file(s) with issues\n", len(invalidFiles))
-		osExit(1) // Use the variable instead of direct call
-	} else if verbose {
-		fmt.Println("All functions contain the required code block")
+// issueLabel describes what the per-file counts in the summary are counting
+func issueLabel(rules []*Rule) string {
+	if len(rules) == 1 && rules[0].Type == ruleTypeCodeBlock && rules[0].Min == nil && rules[0].Max == nil {
+		if rules[0].Invert {
+			return "function(s) containing forbidden code block"
+		}
+		return "function(s) missing required code block"
 	}
+	return "issue(s)"
 }
 
 // findFiles finds all files matching the given pattern
@@ -203,10 +649,9 @@ func shouldIgnore(path string, ignorePaths []string) bool {
 	return false
 }
 
-// Fix the checkExportedFunctions function to properly handle inverted search
-func checkExportedFunctions(rootNode *sitter.Node, content []byte, codeBlock string, isRegex bool, filePath string, invertSearch bool, verbose bool) (bool, int) {
-	// Create query to find exported functions, including arrow functions and function expressions
-	queryStr := `
+// Tree-sitter queries used to select each type of function
+const (
+	exportedFunctionsQuery = `
 	(export_statement
 		(function_declaration) @func)
 	(export_statement
@@ -219,141 +664,94 @@ func checkExportedFunctions(rootNode *sitter.Node, content []byte, codeBlock str
 				value: (function_expression) @func_expr)))
 	`
 
-	query, err := sitter.NewQuery([]byte(queryStr), typescript.GetLanguage())
-	if err != nil {
-		fmt.Printf("Error creating query: %v\n", err)
-		return false, 0
-	}
-
-	cursor := sitter.NewQueryCursor()
-	cursor.Exec(query, rootNode)
-
-	allPass := true
-	issueCount := 0
-	totalFunctions := 0
-
-	for {
-		match, ok := cursor.NextMatch()
-		if !ok {
-			break
-		}
-
-		for _, capture := range match.Captures {
-			totalFunctions++
-			funcNode := capture.Node
-
-			// Check if the function has an ignore comment
-			if hasIgnoreComment(content, funcNode) {
-				if verbose {
-					fmt.Printf("%s:%d - Skipping function due to @ts-analyzer-ignore comment\n",
-						filePath, funcNode.StartPoint().Row+1)
-				}
-				continue
-			}
-
-			funcContent := string(content[funcNode.StartByte():funcNode.EndByte()])
+	internalFunctionsQuery = `
+		(function_declaration) @func
+		(method_definition) @method
+		(lexical_declaration
+			(variable_declarator
+				name: (identifier) @var_name
+				value: (function_expression) @func_expr))
+		(lexical_declaration
+			(variable_declarator
+				name: (identifier) @var_name
+				value: (arrow_function) @arrow_func))
+	`
 
-			if verbose {
-				fmt.Println("Checking function content:")
-				fmt.Println(funcContent)
-				fmt.Println("Looking for code block:", codeBlock)
-				if isRegex {
-					fmt.Println("Using regex matching")
-				}
-			}
+	callbackFunctionsQuery = `
+		(call_expression
+			arguments: (arguments
+				(arrow_function) @callback_arrow))
+		(call_expression
+			arguments: (arguments
+				(function_expression) @callback_func))
+	`
 
-			hasCodeBlock := false
+	allFunctionsQuery = `
+		(function_declaration) @func
+		(arrow_function) @arrow
+		(method_definition) @method
+		(lexical_declaration
+			(variable_declarator
+				value: (function_expression))) @func_var
+	`
+)
 
-			if isRegex {
-				re, err := regexp.Compile(codeBlock)
-				if err != nil {
-					fmt.Printf("Error compiling regex: %v\n", err)
-					continue
-				}
+// functionQueries maps each function type to the query that selects it
+var functionQueries = map[string]string{
+	"exported": exportedFunctionsQuery,
+	"internal": internalFunctionsQuery,
+	"callback": callbackFunctionsQuery,
+	"all":      allFunctionsQuery,
+}
 
-				// Check each line for the regex pattern
-				lines := strings.Split(funcContent, "\n")
-				for _, line := range lines {
-					// Skip comment lines
-					trimmedLine := strings.TrimSpace(line)
-					if strings.HasPrefix(trimmedLine, "//") || strings.HasPrefix(trimmedLine, "/*") {
-						continue
-					}
-
-					if re.MatchString(line) {
-						hasCodeBlock = true
-						break
-					}
-				}
-			} else {
-				// Check each line for the exact code block
-				lines := strings.Split(funcContent, "\n")
-				for _, line := range lines {
-					// Skip comment lines
-					trimmedLine := strings.TrimSpace(line)
-					if strings.HasPrefix(trimmedLine, "//") || strings.HasPrefix(trimmedLine, "/*") {
-						continue
-					}
-
-					if strings.Contains(line, codeBlock) {
-						hasCodeBlock = true
-						break
-					}
-				}
-			}
+// Compiled functionQueries, shared by every file
+var (
+	compiledFunctionQueries     map[string]*sitter.Query
+	compiledFunctionQueriesOnce sync.Once
+	compiledFunctionQueriesErr  error
+)
 
-			// For inverted search, we're looking for functions that DON'T have the code block
-			// So we invert the condition
-			if invertSearch {
-				if hasCodeBlock {
-					allPass = false
-					issueCount++
-					fmt.Printf("%s:%d - Contains forbidden code block\n",
-						filePath, funcNode.StartPoint().Row+1)
-				}
-			} else {
-				if !hasCodeBlock {
-					allPass = false
-					issueCount++
-					fmt.Printf("%s:%d - Missing required code block\n",
-						filePath, funcNode.StartPoint().Row+1)
-				}
+// getFunctionQuery returns the compiled query for a function type. All queries
+// are compiled on first use.
+func getFunctionQuery(fnType string) (*sitter.Query, error) {
+	compiledFunctionQueriesOnce.Do(func() {
+		compiledFunctionQueries = make(map[string]*sitter.Query)
+		for name, queryStr := range functionQueries {
+			query, err := sitter.NewQuery([]byte(queryStr), typescript.GetLanguage())
+			if err != nil {
+				compiledFunctionQueriesErr = fmt.Errorf("compiling %s functions query: %w", name, err)
+				return
 			}
+			compiledFunctionQueries[name] = query
 		}
-	}
-
-	return allPass, issueCount
-}
+	})
 
-func checkAllFunctions(node *sitter.Node, content []byte, codeBlock string, isRegex bool, filename string, invert bool, verbose bool) (bool, int) {
-	if node == nil {
-		fmt.Println("Error: nil node passed to checkAllFunctions")
-		return false, 0
+	if compiledFunctionQueriesErr != nil {
+		return nil, compiledFunctionQueriesErr
 	}
 
-	allFunctionsValid := true
-	issueCount := 0
-
-	// Query to find all functions
-	queryStr := `
-		(function_declaration) @func
-		(arrow_function) @arrow
-		(method_definition) @method
-		(lexical_declaration
-			(variable_declarator
-				value: (function_expression))) @func_var
-	`
+	query, ok := compiledFunctionQueries[fnType]
+	if !ok {
+		return nil, fmt.Errorf("unknown function type %q", fnType)
+	}
+	return query, nil
+}
 
-	query, err := sitter.NewQuery([]byte(queryStr), typescript.GetLanguage())
+// selectFunctions returns the function nodes of the given type in the order the query finds them
+func selectFunctions(rootNode *sitter.Node, fnType string) ([]*sitter.Node, error) {
+	query, err := getFunctionQuery(fnType)
 	if err != nil {
-		fmt.Printf("Error creating query: %v\n", err)
-		return false, 0
+		return nil, err
 	}
 
 	cursor := sitter.NewQueryCursor()
-	cursor.Exec(query, node)
+	cursor.Exec(query, rootNode)
+
+	var functions []*sitter.Node
+
+	// Track functions we've already seen to avoid duplicates
+	seen := make(map[uint32]bool)
 
-	foundAnyFunction := false
 	for {
 		match, ok := cursor.NextMatch()
 		if !ok {
@@ -361,371 +759,405 @@ func checkAllFunctions(node *sitter.Node, content []byte, codeBlock string, isRe
 		}
 
 		for _, capture := range match.Captures {
-			foundAnyFunction = true
-			funcNode := capture.Node
+			// Skip variable names, only process function nodes
+			if capture.Node.Type() == "identifier" {
+				continue
+			}
 
-			// Check if the function has an ignore comment
-			if hasIgnoreComment(content, funcNode) {
-				if verbose {
-					fmt.Printf("%s:%d - Skipping function due to @ts-analyzer-ignore comment\n",
-						filename, funcNode.StartPoint().Row+1)
-				}
+			funcNode := capture.Node
+			if seen[funcNode.StartByte()] {
 				continue
 			}
+			seen[funcNode.StartByte()] = true
 
-			funcContent := string(content[funcNode.StartByte():funcNode.EndByte()])
-
-			// Check if the code block is properly used
-			hasCodeBlock := isCodeBlockUsedInFunction(funcContent, codeBlock, isRegex, verbose)
-
-			// If inverted, we want functions that DON'T have the code block
-			// If not inverted, we want functions that DO have the code block
-			if (!invert && !hasCodeBlock) || (invert && hasCodeBlock) {
-				allFunctionsValid = false
-				issueCount++
-				if invert {
-					fmt.Printf("%s:%d - Contains forbidden code block\n",
-						filename, funcNode.StartPoint().Row+1)
-				} else {
-					fmt.Printf("%s:%d - Missing required code block\n",
-						filename, funcNode.StartPoint().Row+1)
-				}
+			// Internal functions are the ones that are not exported
+			if fnType == "internal" && isExportedFunction(funcNode, rootNode) {
+				continue
 			}
+
+			functions = append(functions, funcNode)
 		}
 	}
 
-	// If no functions were found, return true (nothing to check)
-	if !foundAnyFunction && verbose {
-		fmt.Println("No functions found in the file")
+	return functions, nil
+}
+
+// checkFunctions applies the rules to every function of the given type and
+// prints a line for each finding
+func checkFunctions(rootNode *sitter.Node, content []byte, fnType string, rules []*Rule, filename string) (bool, int) {
+	findings, err := collectFindings(rootNode, content, fnType, rules, filename, nil)
+	if err != nil {
+		logger.Error("checking functions", "error", err)
+		return false, 0
 	}
 
-	return allFunctionsValid || !foundAnyFunction, issueCount
+	printFindings(filename, findings)
+	return len(findings) == 0, len(findings)
 }
 
-// isCodeBlockUsedInFunction checks if a code block is properly used within a function
-func isCodeBlockUsedInFunction(funcContent string, codeBlock string, isRegex bool, verbose bool) bool {
-	if verbose {
-		fmt.Printf("Checking function content:\n%s\n", funcContent)
-		fmt.Printf("Looking for code block: %s\n", codeBlock)
-		if isRegex {
-			fmt.Printf("Using regex matching\n")
-		}
+// collectFindings applies the rules to every function of the given type and
+// returns the findings in source order. When checked is not nil, it records
+// the start byte of the functions each rule was applied to, and a function a
+// rule already checked as another type is not checked again.
+func collectFindings(rootNode *sitter.Node, content []byte, fnType string, rules []*Rule, filename string, checked map[*Rule]map[uint32]bool) ([]Finding, error) {
+	if rootNode == nil {
+		return nil, fmt.Errorf("nil node passed while checking %s functions for file %s", fnType, filename)
 	}
 
-	// If using regex, compile the pattern
-	if isRegex {
-		pattern, err := regexp.Compile(codeBlock)
+	var functions []*sitter.Node
+	if fnType == publicAPIFnType {
+		functions = selectPublicFunctions(rootNode, content, filename)
+	} else {
+		var err error
+		functions, err = selectFunctions(rootNode, fnType)
 		if err != nil {
-			fmt.Printf("Error compiling regex pattern: %v\n", err)
-			return false
+			return nil, fmt.Errorf("creating query for file %s: %w", filename, err)
 		}
+	}
 
-		// First, check if the code block exists at all using regex
-		if !pattern.MatchString(funcContent) {
-			if verbose {
-				fmt.Printf("Regex pattern not found in function\n")
-			}
-			return false
-		}
+	if len(functions) == 0 {
+		logger.Debug("no functions found", "file", filename, "type", fnType)
+	}
 
-		// The code block exists, now check if it's in a comment
-		lines := strings.Split(funcContent, "\n")
-		for _, line := range lines {
-			trimmedLine := strings.TrimSpace(line)
+	var findings []Finding
 
-			// Skip empty lines
-			if trimmedLine == "" {
-				continue
-			}
+	// Index the lines once so ignore comments are found without rescanning the file
+	lines := newLineIndex(content)
 
-			// Check if line matches the pattern but is not a comment
-			if pattern.MatchString(line) && !strings.HasPrefix(trimmedLine, "//") && !strings.HasPrefix(trimmedLine, "/*") {
-				if verbose {
-					fmt.Printf("Found regex match in non-comment line: %s\n", line)
-				}
-				return true
-			}
-		}
-	} else {
-		// Original string-based matching
-		// First, check if the code block exists at all
-		if !strings.Contains(funcContent, codeBlock) {
-			if verbose {
-				fmt.Printf("Code block not found in function\n")
-			}
-			return false
+	for _, funcNode := range functions {
+		// Check if the function has an ignore comment
+		if hasIgnoreComment(content, lines, funcNode) {
+			logger.Debug("skipping function with @ts-analyzer-ignore comment", "file", filename, "line", funcNode.StartPoint().Row+1)
+			continue
 		}
 
-		// The code block exists, now check if it's in a comment
-		lines := strings.Split(funcContent, "\n")
-		for _, line := range lines {
-			trimmedLine := strings.TrimSpace(line)
-
-			// Skip empty lines
-			if trimmedLine == "" {
+		for _, rule := range rules {
+			if checked[rule][funcNode.StartByte()] {
 				continue
 			}
-
-			// Check if line contains the code block but is not a comment
-			if strings.Contains(line, codeBlock) && !strings.HasPrefix(trimmedLine, "//") && !strings.HasPrefix(trimmedLine, "/*") {
-				if verbose {
-					fmt.Printf("Found code block in non-comment line: %s\n", line)
+			selected, err := rule.selects(funcNode, rootNode, content, fnType, filename)
+			if err != nil {
+				return nil, err
+			}
+			if !selected {
+				continue
+			}
+			if checked != nil {
+				if checked[rule] == nil {
+					checked[rule] = make(map[uint32]bool)
 				}
-				return true
+				checked[rule][funcNode.StartByte()] = true
+			}
+
+			ruleFindings, err := rule.evaluate(funcNode, rootNode, content, fnType, filename)
+			if err != nil {
+				return nil, err
 			}
+			findings = append(findings, rule.withSeverity(ruleFindings)...)
 		}
 	}
 
-	if verbose {
-		fmt.Printf("Code block only found in comments or not found at all\n")
+	return findings, nil
+}
+
+// printFindings prints one line per finding
+func printFindings(filename string, findings []Finding) {
+	for _, finding := range findings {
+		fmt.Println(formatFinding(filename, finding))
 	}
-	return false
 }
 
-func processTypeScriptFile(filename string, codeBlock string, isRegex bool, invert bool, fnTypes map[string]bool, verbose bool) (bool, int) {
-	// Get absolute path for consistent reporting
-	absPath, err := filepath.Abs(filename)
+// singleCodeBlockRule identifies a rule built from the arguments of the
+// single code block check functions
+type singleCodeBlockRule struct {
+	codeBlock string
+	isRegex   bool
+	invert    bool
+	fnTypes   string
+}
+
+// Rules of the single code block check functions, compiled on first use so
+// that checking many files does not compile the same pattern again
+var singleCodeBlockRules = struct {
+	sync.Mutex
+	rules map[singleCodeBlockRule]*Rule
+}{
+	rules: make(map[singleCodeBlockRule]*Rule),
+}
+
+// codeBlockRule returns the compiled code block rule for the arguments of a
+// single code block check function
+func codeBlockRule(codeBlock string, isRegex bool, invert bool, fnTypes string) (*Rule, error) {
+	singleCodeBlockRules.Lock()
+	defer singleCodeBlockRules.Unlock()
+
+	key := singleCodeBlockRule{codeBlock: codeBlock, isRegex: isRegex, invert: invert, fnTypes: fnTypes}
+	if rule, ok := singleCodeBlockRules.rules[key]; ok {
+		return rule, nil
+	}
+	rule, err := compileCodeBlockRule(codeBlock, isRegex, invert, fnTypes)
 	if err != nil {
-		// If we can't get absolute path, use the original filename
-		absPath = filename
+		return nil, err
 	}
+	singleCodeBlockRules.rules[key] = rule
+	return rule, nil
+}
 
-	content, err := os.ReadFile(filename)
+// checkExportedFunctions checks a single code block against every exported function
+func checkExportedFunctions(rootNode *sitter.Node, content []byte, codeBlock string, isRegex bool, filePath string, invertSearch bool) (bool, int) {
+	rule, err := codeBlockRule(codeBlock, isRegex, invertSearch, "exported")
 	if err != nil {
-		fmt.Printf("Error reading file %s: %v\n", absPath, err)
+		logger.Error("checking functions", "error", err)
 		return false, 0
 	}
+	return checkFunctions(rootNode, content, "exported", []*Rule{rule}, filePath)
+}
 
-	// Parse the file with tree-sitter
-	parser := sitter.NewParser()
-	parser.SetLanguage(typescript.GetLanguage())
+// checkAllFunctions checks a single code block against every function regardless of type
+func checkAllFunctions(node *sitter.Node, content []byte, codeBlock string, isRegex bool, filename string, invert bool) (bool, int) {
+	rule, err := codeBlockRule(codeBlock, isRegex, invert, "exported,internal,callback")
+	if err != nil {
+		logger.Error("checking functions", "error", err)
+		return false, 0
+	}
+	return checkFunctions(node, content, "all", []*Rule{rule}, filename)
+}
 
-	tree := parser.Parse(nil, content)
-	rootNode := tree.RootNode()
+// checkInternalFunctions checks a single code block against every non-exported function
+func checkInternalFunctions(node *sitter.Node, content []byte, codeBlock string, isRegex bool, filename string, invert bool) (bool, int) {
+	rule, err := codeBlockRule(codeBlock, isRegex, invert, "internal")
+	if err != nil {
+		logger.Error("checking functions", "error", err)
+		return false, 0
+	}
+	return checkFunctions(node, content, "internal", []*Rule{rule}, filename)
+}
 
-	allValid := true
-	totalIssues := 0
+// checkCallbackFunctions checks a single code block against every function passed as an argument
+func checkCallbackFunctions(node *sitter.Node, content []byte, codeBlock string, isRegex bool, filename string, invert bool) (bool, int) {
+	rule, err := codeBlockRule(codeBlock, isRegex, invert, "callback")
+	if err != nil {
+		logger.Error("checking functions", "error", err)
+		return false, 0
+	}
+	return checkFunctions(node, content, "callback", []*Rule{rule}, filename)
+}
 
-	// Check each requested function type
-	if fnTypes["exported"] {
-		valid, issues := checkExportedFunctions(rootNode, content, codeBlock, isRegex, absPath, invert, verbose)
-		if !valid {
-			allValid = false
+// isCodeBlockUsedInFunction checks if a code block is properly used within a function
+func isCodeBlockUsedInFunction(funcContent string, codeBlock string, isRegex bool) bool {
+	// The regex is compiled once, with the rule for the code block
+	var pattern *regexp.Regexp
+	if isRegex {
+		rule, err := codeBlockRule(codeBlock, true, false, "exported")
+		if err != nil {
+			logger.Error("compiling regex pattern", "error", err)
+			return false
 		}
-		totalIssues += issues
+		pattern = rule.codeBlock.re
 	}
 
-	if fnTypes["internal"] {
-		valid, issues := checkInternalFunctions(rootNode, content, codeBlock, isRegex, absPath, invert, verbose)
-		if !valid {
-			allValid = false
+	return codeBlockUsed(funcContent, codeBlock, pattern)
+}
+
+// codeBlockUsed checks if a code block is used outside comments within a
+// function. The code block is matched as a regular expression when pattern is set.
+func codeBlockUsed(funcContent string, codeBlock string, pattern *regexp.Regexp) bool {
+	logger.Debug("looking for code block", "code_block", codeBlock, "regex", pattern != nil)
+
+	matches := func(text string) bool {
+		if pattern != nil {
+			return pattern.MatchString(text)
 		}
-		totalIssues += issues
+		return strings.Contains(text, codeBlock)
 	}
 
-	if fnTypes["callback"] {
-		valid, issues := checkCallbackFunctions(rootNode, content, codeBlock, isRegex, absPath, invert, verbose)
-		if !valid {
-			allValid = false
-		}
-		totalIssues += issues
+	// First, check if the code block exists at all
+	if !matches(funcContent) {
+		logger.Debug("code block not found in function")
+		return false
 	}
 
-	return allValid, totalIssues
-}
+	// The code block exists, now check if it's in a comment
+	for _, line := range strings.Split(funcContent, "\n") {
+		trimmedLine := strings.TrimSpace(line)
 
-// parseFunctionTypes parses the comma-separated function types string
-func parseFunctionTypes(fnTypes string) map[string]bool {
-	result := make(map[string]bool)
-	types := strings.Split(fnTypes, ",")
+		// Skip empty lines
+		if trimmedLine == "" {
+			continue
+		}
 
-	for _, t := range types {
-		t = strings.TrimSpace(t)
-		if t == "exported" || t == "internal" || t == "callback" {
-			result[t] = true
+		// Check if line contains the code block but is not a comment
+		if matches(line) && !strings.HasPrefix(trimmedLine, "//") && !strings.HasPrefix(trimmedLine, "/*") {
+			logger.Debug("found code block in non-comment line", "line", trimmedLine)
+			return true
 		}
 	}
 
-	return result
+	logger.Debug("code block only found in comments")
+	return false
 }
 
-// Add a new function to check internal (non-exported) functions
-func checkInternalFunctions(node *sitter.Node, content []byte, codeBlock string, isRegex bool, filename string, invert bool, verbose bool) (bool, int) {
-	if node == nil {
-		fmt.Printf("Error: nil node passed to checkInternalFunctions for file %s\n", filename)
+// processTypeScriptFile applies every rule to a file, reusing cached results
+// when the cache has them, and prints the findings
+func processTypeScriptFile(filename string, rules []*Rule, cache *resultCache) (bool, int) {
+	absPath, result, err := checkFile(filename, rules, cache)
+	if err != nil {
+		logger.Error("analyzing file", "file", absPath, "error", err)
 		return false, 0
 	}
 
-	allFunctionsValid := true
-	issueCount := 0
+	printFindings(absPath, result.Findings)
+	return len(result.Findings) == 0, len(result.Findings)
+}
 
-	// Query to find non-exported functions
-	queryStr := `
-		(function_declaration) @func
-		(method_definition) @method
-		(lexical_declaration
-			(variable_declarator
-				name: (identifier) @var_name
-				value: (function_expression) @func_expr))
-		(lexical_declaration
-			(variable_declarator
-				name: (identifier) @var_name
-				value: (arrow_function) @arrow_func))
-	`
+// checkFile applies every rule to a file, reusing cached results when the
+// cache has them. It returns the absolute path used in reports, and an error
+// when the file cannot be checked.
+func checkFile(filename string, rules []*Rule, cache *resultCache) (string, *fileResult, error) {
+	// Get absolute path for consistent reporting
+	absPath, err := filepath.Abs(filename)
+	if err != nil {
+		// If we can't get absolute path, use the original filename
+		absPath = filename
+	}
 
-	query, err := sitter.NewQuery([]byte(queryStr), typescript.GetLanguage())
+	content, err := os.ReadFile(filename)
 	if err != nil {
-		fmt.Printf("Error creating query for file %s: %v\n", filename, err)
-		return false, 0
+		return absPath, nil, fmt.Errorf("reading file: %w", err)
 	}
 
-	cursor := sitter.NewQueryCursor()
-	cursor.Exec(query, node)
+	var cacheKey string
+	if cache != nil {
+		cacheKey = cache.key(absPath, content)
+		if result, ok := cache.get(cacheKey); ok {
+			logger.Debug("using cached results", "file", absPath)
+			return absPath, result, nil
+		}
+	}
 
-	// Track functions we've already checked to avoid duplicates
-	checkedFunctions := make(map[string]bool)
+	result, err := analyzeContent(content, rules, absPath)
+	if err != nil {
+		return absPath, nil, err
+	}
 
-	for {
-		match, ok := cursor.NextMatch()
-		if !ok {
-			break
+	if cache != nil {
+		if err := cache.put(cacheKey, result); err != nil {
+			logger.Warn("writing cache", "file", absPath, "error", err)
 		}
+	}
 
-		for _, capture := range match.Captures {
-			// Skip variable names, only process function nodes
-			if capture.Node.Type() == "identifier" {
-				continue
-			}
+	return absPath, result, nil
+}
 
-			funcNode := capture.Node
-			startByte := funcNode.StartByte()
+// analyzeContent parses a file and applies every rule to it
+func analyzeContent(content []byte, rules []*Rule, filename string) (*fileResult, error) {
+	// Parse the file with tree-sitter
+	parser := sitter.NewParser()
+	parser.SetLanguage(typescript.GetLanguage())
 
-			// Create a unique key for this function
-			funcKey := fmt.Sprintf("%d", startByte)
+	tree := parser.Parse(nil, content)
+	rootNode := tree.RootNode()
 
-			// Skip if we've already checked this function or if it's an exported function
-			if checkedFunctions[funcKey] || isExportedFunction(funcNode, node) {
-				continue
-			}
-			checkedFunctions[funcKey] = true
+	// Files with syntax errors are still checked, but parts of them may be missed
+	result := &fileResult{}
+	if rootNode.HasError() {
+		result.SyntaxError = &syntaxError{}
+		if node := firstSyntaxError(rootNode); node != nil {
+			result.SyntaxError.Line = node.StartPoint().Row + 1
+			result.SyntaxError.Column = node.StartPoint().Column + 1
+		}
+		logger.Debug("syntax error, results may be incomplete", "file", filename, "line", result.SyntaxError.Line, "column", result.SyntaxError.Column)
+	}
 
-			// Check if the function has an ignore comment
-			if hasIgnoreComment(content, funcNode) {
-				if verbose {
-					fmt.Printf("%s:%d - Skipping function due to @ts-analyzer-ignore comment\n",
-						filename, funcNode.StartPoint().Row+1)
-				}
-				continue
-			}
+	var findings []Finding
 
-			funcContent := string(content[startByte:funcNode.EndByte()])
-			lineNum := funcNode.StartPoint().Row + 1
-
-			// Check if the code block is properly used
-			hasCodeBlock := isCodeBlockUsedInFunction(funcContent, codeBlock, isRegex, verbose)
-
-			// If inverted, we want functions that DON'T have the code block
-			// If not inverted, we want functions that DO have the code block
-			if (!invert && !hasCodeBlock) || (invert && hasCodeBlock) {
-				allFunctionsValid = false
-				issueCount++
-				if invert {
-					fmt.Printf("%s:%d - Contains forbidden code block\n",
-						filename, lineNum)
-				} else {
-					fmt.Printf("%s:%d - Missing required code block\n",
-						filename, lineNum)
-				}
+	// Import boundaries and cycles apply to the whole file rather than to functions
+	for _, rule := range rules {
+		if rule.fileLevel() {
+			ruleFindings, err := rule.checkFile(rootNode, content, filename)
+			if err != nil {
+				return nil, err
 			}
+			findings = append(findings, rule.withSeverity(ruleFindings)...)
 		}
 	}
 
-	return allFunctionsValid, issueCount
-}
+	// Check each function type against the rules that select it, once per
+	// function even when it has several of the types a rule selects
+	checked := make(map[*Rule]map[uint32]bool)
+	for _, fnType := range []string{"exported", "internal", "callback", publicAPIFnType} {
+		var selected []*Rule
+		for _, rule := range rules {
+			if !rule.fileLevel() && parseFunctionTypes(rule.FnTypes)[fnType] {
+				selected = append(selected, rule)
+			}
+		}
+		if len(selected) == 0 {
+			continue
+		}
 
-// Add a new function to check callback functions
-func checkCallbackFunctions(node *sitter.Node, content []byte, codeBlock string, isRegex bool, filename string, invert bool, verbose bool) (bool, int) {
-	if node == nil {
-		fmt.Printf("Error: nil node passed to checkCallbackFunctions for file %s\n", filename)
-		return false, 0
+		typeFindings, err := collectFindings(rootNode, content, fnType, selected, filename, checked)
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, typeFindings...)
 	}
 
-	allFunctionsValid := true
-	issueCount := 0
+	result.Findings = findings
+	return result, nil
+}
 
-	// Query to find callback functions (functions passed as arguments)
-	queryStr := `
-		(call_expression
-			arguments: (arguments
-				(arrow_function) @callback_arrow))
-		(call_expression
-			arguments: (arguments
-				(function_expression) @callback_func))
-	`
+// syntaxError is where the first syntax error of a file starts, zero when
+// the parser did not mark where
+type syntaxError struct {
+	Line   uint32 `json:"line,omitempty"`
+	Column uint32 `json:"column,omitempty"`
+}
 
-	query, err := sitter.NewQuery([]byte(queryStr), typescript.GetLanguage())
-	if err != nil {
-		fmt.Printf("Error creating query for file %s: %v\n", filename, err)
-		return false, 0
+// firstSyntaxError returns the first ERROR or MISSING node of a tree, or nil
+// when it has none
+func firstSyntaxError(node *sitter.Node) *sitter.Node {
+	if !node.HasError() {
+		return nil
+	}
+	if node.IsError() || node.IsMissing() {
+		return node
 	}
 
-	cursor := sitter.NewQueryCursor()
-	cursor.Exec(query, node)
-
-	// Track functions we've already checked to avoid duplicates
-	checkedFunctions := make(map[string]bool)
-
-	for {
-		match, ok := cursor.NextMatch()
-		if !ok {
-			break
+	for i := 0; i < int(node.ChildCount()); i++ {
+		if found := firstSyntaxError(node.Child(i)); found != nil {
+			return found
 		}
+	}
+	return nil
+}
 
-		for _, capture := range match.Captures {
-			funcNode := capture.Node
-			startByte := funcNode.StartByte()
-
-			// Create a unique key for this function
-			funcKey := fmt.Sprintf("%d", startByte)
+// parseFunctionTypes parses the comma-separated function types string
+func parseFunctionTypes(fnTypes string) map[string]bool {
+	result := make(map[string]bool)
+	types := strings.Split(fnTypes, ",")
 
-			// Skip if we've already checked this function
-			if checkedFunctions[funcKey] {
-				continue
-			}
-			checkedFunctions[funcKey] = true
+	for _, t := range types {
+		t = strings.TrimSpace(t)
+		if t == "exported" || t == "internal" || t == "callback" || t == publicAPIFnType {
+			result[t] = true
+		}
+	}
 
-			// Check if the function has an ignore comment
-			if hasIgnoreComment(content, funcNode) {
-				if verbose {
-					fmt.Printf("%s:%d - Skipping function due to @ts-analyzer-ignore comment\n",
-						filename, funcNode.StartPoint().Row+1)
-				}
-				continue
-			}
+	return result
+}
 
-			funcContent := string(content[startByte:funcNode.EndByte()])
-			lineNum := funcNode.StartPoint().Row + 1
-
-			// Check if the code block is properly used
-			hasCodeBlock := isCodeBlockUsedInFunction(funcContent, codeBlock, isRegex, verbose)
-
-			// If inverted, we want functions that DON'T have the code block
-			// If not inverted, we want functions that DO have the code block
-			if (!invert && !hasCodeBlock) || (invert && hasCodeBlock) {
-				allFunctionsValid = false
-				issueCount++
-				if invert {
-					fmt.Printf("%s:%d - Contains forbidden code block\n",
-						filename, lineNum)
-				} else {
-					fmt.Printf("%s:%d - Missing required code block\n",
-						filename, lineNum)
-				}
-			}
+// unknownFunctionTypes returns the entries of a comma-separated function
+// types string that parseFunctionTypes ignores
+func unknownFunctionTypes(fnTypes string) []string {
+	var unknown []string
+	for _, t := range strings.Split(fnTypes, ",") {
+		t = strings.TrimSpace(t)
+		if !parseFunctionTypes(t)[t] {
+			unknown = append(unknown, fmt.Sprintf("%q", t))
 		}
 	}
-
-	return allFunctionsValid, issueCount
+	return unknown
 }
 
 // Helper function to check if a function is exported
@@ -751,7 +1183,7 @@ func isExportedFunction(funcNode *sitter.Node, rootNode *sitter.Node) bool {
 }
 
 // Helper function to check if a function has an ignore comment
-func hasIgnoreComment(content []byte, funcNode *sitter.Node) bool {
+func hasIgnoreComment(content []byte, lines lineIndex, funcNode *sitter.Node) bool {
 	// Get the start line of the function
 	startLine := funcNode.StartPoint().Row
 
@@ -760,10 +1192,7 @@ func hasIgnoreComment(content []byte, funcNode *sitter.Node) bool {
 		return false
 	}
 
-	// Get the content as string and split into lines
-	lines := strings.Split(string(content), "\n")
-
 	// Check the line above the function for the ignore comment
-	prevLine := lines[startLine-1]
-	return strings.Contains(prevLine, "// @ts-analyzer-ignore")
+	prevLine := lines.line(content, startLine-1)
+	return bytes.Contains(prevLine, []byte("// @ts-analyzer-ignore"))
 }
@@ -442,7 +442,7 @@ const functionExpression = function() {
     if testing.Verbose() {
         t.Log("Testing with one function missing required code")
     }
-    result, _ := checkAllFunctions(rootNode, content, "requiredCode", false, testFile, false, false)
+    result, _ := checkAllFunctions(rootNode, content, "requiredCode", false, testFile, false)
     if result {
         t.Error("Expected checkAllFunctions to return false when at least one function is missing the code block")
     }
@@ -495,7 +495,7 @@ const functionExpression = function() {
     if testing.Verbose() {
         t.Log("Testing with all functions having required code")
     }
-    result, _ = checkAllFunctions(rootNode, content, "requiredCode", false, testFile, false, false)
+    result, _ = checkAllFunctions(rootNode, content, "requiredCode", false, testFile, false)
     if !result {
         t.Error("Expected checkAllFunctions to return true when all functions have the code block")
     }
@@ -671,7 +671,7 @@ export const funcExprWithoutForbiddenCode = function() {
     if testing.Verbose() {
         t.Log("Testing inverted search - looking for functions containing forbidden code")
     }
-    result, _ := checkExportedFunctions(rootNode, content, "forbiddenCode", false, testFile, true, false)
+    result, _ := checkExportedFunctions(rootNode, content, "forbiddenCode", false, testFile, true)
     if result {
         t.Error("Expected checkExportedFunctions with inverted search to return false when functions contain the forbidden code")
     }
@@ -725,7 +725,7 @@ export const funcExprTwo = function() {
     if testing.Verbose() {
         t.Log("Testing inverted search - no functions should contain forbidden code")
     }
-    result, _ = checkExportedFunctions(rootNode, content, "forbiddenCode", false, testFile, true, false)
+    result, _ = checkExportedFunctions(rootNode, content, "forbiddenCode", false, testFile, true)
     if !result {
         t.Error("Expected checkExportedFunctions with inverted search to return true when no functions contain the forbidden code")
     }
@@ -772,7 +772,7 @@ function main() {
     if testing.Verbose() {
         t.Log("Testing with callbacks having required code")
     }
-    result, _ := checkCallbackFunctions(rootNode, content, "requiredCode", false, testFile, false, false)
+    result, _ := checkCallbackFunctions(rootNode, content, "requiredCode", false, testFile, false)
     if !result {
         t.Error("Expected checkCallbackFunctions to return true when all callbacks have the code block")
     }
@@ -808,7 +808,7 @@ function main() {
     if testing.Verbose() {
         t.Log("Testing with callbacks missing required code")
     }
-    result, _ = checkCallbackFunctions(rootNode, content, "requiredCode", false, testFile, false, false)
+    result, _ = checkCallbackFunctions(rootNode, content, "requiredCode", false, testFile, false)
     if result {
         t.Error("Expected checkCallbackFunctions to return false when callbacks are missing the code block")
     }
@@ -844,7 +844,7 @@ function main() {
     if testing.Verbose() {
         t.Log("Testing inverted search for forbidden code")
     }
-    result, _ = checkCallbackFunctions(rootNode, content, "forbiddenCode", false, testFile, true, false)
+    result, _ = checkCallbackFunctions(rootNode, content, "forbiddenCode", false, testFile, true)
     if result {
         t.Error("Expected checkCallbackFunctions with inverted search to return false when a callback contains forbidden code")
     }
@@ -947,7 +947,7 @@ function test() {
             }()
 
             // Test with the pattern
-            result, issueCount := checkAllFunctions(rootNode, content, tc.pattern, tc.isRegex, testFile, false, false)
+            result, issueCount := checkAllFunctions(rootNode, content, tc.pattern, tc.isRegex, testFile, false)
 
             if result != tc.expectedMatch {
                 t.Errorf("Expected result to be %v for pattern '%s', got %v with %d issues",
@@ -1053,7 +1053,7 @@ func TestIsCodeBlockUsedInFunction(t *testing.T) {
     for _, tc := range testCases {
         t.Run(tc.name, func(t *testing.T) {
             // Test the function with the pattern
-            result := isCodeBlockUsedInFunction(tc.functionCode, tc.codeBlock, tc.isRegex, false)
+            result := isCodeBlockUsedInFunction(tc.functionCode, tc.codeBlock, tc.isRegex)
 
             if result != tc.expectedResult {
                 t.Errorf("Expected isCodeBlockUsedInFunction to return %v, got %v",
@@ -1116,8 +1116,8 @@ export function anotherFunctionWithoutCodeBlock() {
         os.Stdout = oldStdout
     }()
 
-    // Test with the ignore comment - use false for verbose to avoid debug output
-    result, issueCount := checkExportedFunctions(rootNode, content, "requiredCode", false, testFile, false, false)
+    // Test with the ignore comment
+    result, issueCount := checkExportedFunctions(rootNode, content, "requiredCode", false, testFile, false)
 
     // We should have 2 issues (the first and third functions), but not the second one with the ignore comment
     if issueCount != 2 {
@@ -1166,7 +1166,7 @@ export const arrowFunctionWithIgnore = () => {
     }()
 
     // Test with the ignore comment for arrow functions
-    result, issueCount = checkExportedFunctions(rootNode, content, "requiredCode", false, testFile, false, false)
+    result, issueCount = checkExportedFunctions(rootNode, content, "requiredCode", false, testFile, false)
 
     // We should have 1 issue (the first function), but not the second one with the ignore comment
     if issueCount != 1 {
@@ -1476,3 +1476,75 @@ type exitError struct {
 func (e exitError) Error() string {
     return fmt.Sprintf("exit with code %d", e.code)
 }
+
+func TestRunExitCode(t *testing.T) {
+    if code := runExitCode(true, nil); code != exitClean {
+        t.Errorf("Expected %d for a clean run, got %d", exitClean, code)
+    }
+    if code := runExitCode(false, nil); code != exitViolations {
+        t.Errorf("Expected %d for violations, got %d", exitViolations, code)
+    }
+
+    // Analysis errors win over violations
+    errors := map[string]error{"a.ts": fmt.Errorf("reading file")}
+    if code := runExitCode(false, errors); code != exitAnalysisError {
+        t.Errorf("Expected %d for analysis errors, got %d", exitAnalysisError, code)
+    }
+}
+
+func TestCheckFileErrors(t *testing.T) {
+    rule, err := compileCodeBlockRule("requiredCode", false, false, "exported")
+    if err != nil {
+        t.Fatalf("Failed to compile rule: %v", err)
+    }
+
+    missing := filepath.Join(t.TempDir(), "missing.ts")
+    if _, _, err := checkFile(missing, []*Rule{rule}, nil); err == nil {
+        t.Error("Expected an error for a file that cannot be read")
+    }
+}
+
+func TestSyntaxErrorWarning(t *testing.T) {
+    rule, err := compileCodeBlockRule("requiredCode", false, false, "exported")
+    if err != nil {
+        t.Fatalf("Failed to compile rule: %v", err)
+    }
+
+    // The broken file is still checked
+    content := []byte("export function ok() {\n    requiredCode();\n}\n\nexport function broken( {\n}\n")
+    result, err := analyzeContent(content, []*Rule{rule}, "broken.ts")
+    if err != nil {
+        t.Fatalf("Failed to analyze: %v", err)
+    }
+    if result.SyntaxError == nil || result.SyntaxError.Line != 5 {
+        t.Errorf("Expected a syntax error on line 5, got %+v", result.SyntaxError)
+    }
+
+    result, err = analyzeContent([]byte("export function ok() {\n    requiredCode();\n}\n"), []*Rule{rule}, "ok.ts")
+    if err != nil {
+        t.Fatalf("Failed to analyze: %v", err)
+    }
+    if result.SyntaxError != nil {
+        t.Errorf("Expected no syntax error for a valid file, got %+v", result.SyntaxError)
+    }
+
+    // The syntax error is kept with cached results, since the file is not parsed again
+    tempDir := t.TempDir()
+    testFile := filepath.Join(tempDir, "broken.ts")
+    if err := os.WriteFile(testFile, content, 0644); err != nil {
+        t.Fatalf("Failed to write test file: %v", err)
+    }
+    cache, err := newResultCache(filepath.Join(tempDir, "cache"), []*Rule{rule})
+    if err != nil {
+        t.Fatalf("Failed to open cache: %v", err)
+    }
+    for run := 1; run <= 2; run++ {
+        _, result, err := checkFile(testFile, []*Rule{rule}, cache)
+        if err != nil {
+            t.Fatalf("Failed to check file: %v", err)
+        }
+        if result.SyntaxError == nil || result.SyntaxError.Line != 5 {
+            t.Errorf("Run %d: expected a syntax error on line 5, got %+v", run, result.SyntaxError)
+        }
+    }
+}
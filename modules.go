@@ -0,0 +1,451 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// importBinding is a name brought into a file by an import statement
+type importBinding struct {
+	Local    string // Name used in the importing file
+	Imported string // Exported name in the source module, "default" or "*" for namespace imports
+	Source   string // Module specifier as written
+	Line     int
+}
+
+// exportBinding is a name exported by a file
+type exportBinding struct {
+	Exported string // Exported name, "default", or "*" for `export * from`
+	Local    string // Local name, or the imported name for re-exports
+	Source   string // Module specifier for `export ... from` re-exports
+	Line     int
+}
+
+// moduleInfo is what a file imports, exports and declares at the top level
+type moduleInfo struct {
+	imports   []importBinding
+	exports   []exportBinding
+	functions map[string]*sitter.Node
+}
+
+// ImportedSymbol names an export of a module, as in `import { name } from 'module'`
+type ImportedSymbol struct {
+	Module string `yaml:"module" json:"module"`
+	Name   string `yaml:"name" json:"name"`
+}
+
+// String returns the symbol as it appears in reports
+func (s *ImportedSymbol) String() string {
+	return fmt.Sprintf("%s from %q", s.Name, s.Module)
+}
+
+// Local name of an anonymous default export
+const anonymousDefault = "*default*"
+
+// Maximum number of re-export and import hops followed when resolving an export
+const maxReexportHops = 16
+
+// exportResolver follows the names files export, through re-export chains
+// and the imports they re-export, to the top-level declarations they name
+type exportResolver struct {
+	// module returns the parsed file at a path, or nil when it is not known
+	module func(path string) *moduleInfo
+}
+
+// resolve calls visit with the file and local name of each declaration an
+// export name of a file leads to, until visit returns true, and reports
+// whether it did. The name "*" stands for every export; visit is then also
+// called with "*" for the file whose every export is reached.
+func (r exportResolver) resolve(path string, name string, visit func(path string, local string) bool) bool {
+	return r.follow(path, name, 0, make(map[string]bool), visit)
+}
+
+// follow resolves an export name of a file, hops re-exports away from where
+// resolving started
+func (r exportResolver) follow(path string, name string, hops int, visited map[string]bool, visit func(path string, local string) bool) bool {
+	key := path + "\x00" + name
+	if visited[key] || hops > maxReexportHops {
+		return false
+	}
+	visited[key] = true
+
+	info := r.module(path)
+	if info == nil {
+		return false
+	}
+	if name == "*" && visit(path, "*") {
+		return true
+	}
+
+	for _, export := range info.exports {
+		if name != "*" && export.Exported != name && export.Exported != "*" {
+			continue
+		}
+
+		if export.Source == "" {
+			if r.followLocal(path, info, export.Local, hops, visited, visit) {
+				return true
+			}
+			continue
+		}
+
+		source, ok := resolveModule(path, export.Source)
+		if !ok {
+			continue
+		}
+		var found bool
+		switch {
+		case export.Local == "*" && export.Exported != "*":
+			// export * as ns from './module'
+			found = r.follow(source, "*", hops+1, visited, visit)
+		case export.Exported == "*":
+			// export * from './module'
+			found = r.follow(source, name, hops+1, visited, visit)
+		default:
+			found = r.follow(source, export.Local, hops+1, visited, visit)
+		}
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+// followLocal resolves an exported local name, following it when it was imported
+func (r exportResolver) followLocal(path string, info *moduleInfo, local string, hops int, visited map[string]bool, visit func(path string, local string) bool) bool {
+	for _, binding := range info.imports {
+		if binding.Local != local {
+			continue
+		}
+		if source, ok := resolveModule(path, binding.Source); ok {
+			return r.follow(source, binding.Imported, hops+1, visited, visit)
+		}
+		return false
+	}
+	return visit(path, local)
+}
+
+// parseModuleInfo collects the imports, exports and top-level functions of a file
+func parseModuleInfo(rootNode *sitter.Node, content []byte) *moduleInfo {
+	info := &moduleInfo{functions: make(map[string]*sitter.Node)}
+
+	for i := 0; i < int(rootNode.NamedChildCount()); i++ {
+		statement := rootNode.NamedChild(i)
+		line := int(statement.StartPoint().Row) + 1
+
+		switch statement.Type() {
+		case "import_statement":
+			info.imports = append(info.imports, parseImport(statement, content, line)...)
+		case "export_statement":
+			info.parseExport(statement, content, line)
+		default:
+			info.addDeclaration(statement, content, false, line)
+		}
+	}
+
+	return info
+}
+
+// parseImport returns the bindings of an import statement
+func parseImport(statement *sitter.Node, content []byte, line int) []importBinding {
+	sourceNode := statement.ChildByFieldName("source")
+	if sourceNode == nil {
+		return nil
+	}
+	source := stringLiteralValue(sourceNode, content)
+
+	var bindings []importBinding
+	for i := 0; i < int(statement.NamedChildCount()); i++ {
+		clause := statement.NamedChild(i)
+		if clause.Type() != "import_clause" {
+			continue
+		}
+
+		for j := 0; j < int(clause.NamedChildCount()); j++ {
+			child := clause.NamedChild(j)
+			switch child.Type() {
+			case "identifier":
+				bindings = append(bindings, importBinding{Local: nodeText(child, content), Imported: "default", Source: source, Line: line})
+			case "namespace_import":
+				if child.NamedChildCount() > 0 {
+					bindings = append(bindings, importBinding{Local: nodeText(child.NamedChild(0), content), Imported: "*", Source: source, Line: line})
+				}
+			case "named_imports":
+				for k := 0; k < int(child.NamedChildCount()); k++ {
+					specifier := child.NamedChild(k)
+					if specifier.Type() != "import_specifier" {
+						continue
+					}
+					name := nodeText(specifier.ChildByFieldName("name"), content)
+					local := name
+					if alias := specifier.ChildByFieldName("alias"); alias != nil {
+						local = nodeText(alias, content)
+					}
+					bindings = append(bindings, importBinding{Local: local, Imported: name, Source: source, Line: line})
+				}
+			}
+		}
+	}
+
+	// Side-effect imports (import './polyfill') bind nothing but still depend on the module
+	if len(bindings) == 0 {
+		bindings = append(bindings, importBinding{Source: source, Line: line})
+	}
+
+	return bindings
+}
+
+// parseExport records the bindings and declarations of an export statement
+func (info *moduleInfo) parseExport(statement *sitter.Node, content []byte, line int) {
+	var source string
+	if sourceNode := statement.ChildByFieldName("source"); sourceNode != nil {
+		source = stringLiteralValue(sourceNode, content)
+	}
+
+	isDefault := false
+	for i := 0; i < int(statement.ChildCount()); i++ {
+		if statement.Child(i).Type() == "default" {
+			isDefault = true
+		}
+	}
+
+	if declaration := statement.ChildByFieldName("declaration"); declaration != nil {
+		for _, name := range info.addDeclaration(declaration, content, isDefault, line) {
+			exported := name
+			if isDefault {
+				exported = "default"
+			}
+			info.exports = append(info.exports, exportBinding{Exported: exported, Local: name, Line: line})
+		}
+		return
+	}
+
+	if value := statement.ChildByFieldName("value"); value != nil && isDefault {
+		local := anonymousDefault
+		switch value.Type() {
+		case "identifier":
+			local = nodeText(value, content)
+		case "arrow_function", "function_expression", "function":
+			info.functions[anonymousDefault] = value
+		}
+		info.exports = append(info.exports, exportBinding{Exported: "default", Local: local, Line: line})
+		return
+	}
+
+	for i := 0; i < int(statement.NamedChildCount()); i++ {
+		child := statement.NamedChild(i)
+		switch child.Type() {
+		case "export_clause":
+			for j := 0; j < int(child.NamedChildCount()); j++ {
+				specifier := child.NamedChild(j)
+				if specifier.Type() != "export_specifier" {
+					continue
+				}
+				name := nodeText(specifier.ChildByFieldName("name"), content)
+				exported := name
+				if alias := specifier.ChildByFieldName("alias"); alias != nil {
+					exported = nodeText(alias, content)
+				}
+				info.exports = append(info.exports, exportBinding{Exported: exported, Local: name, Source: source, Line: line})
+			}
+		case "namespace_export":
+			if child.NamedChildCount() > 0 {
+				info.exports = append(info.exports, exportBinding{Exported: nodeText(child.NamedChild(0), content), Local: "*", Source: source, Line: line})
+			}
+		}
+	}
+
+	// export * from './module'
+	if source != "" && statement.NamedChildCount() == 1 {
+		info.exports = append(info.exports, exportBinding{Exported: "*", Local: "*", Source: source, Line: line})
+	}
+}
+
+// addDeclaration records the functions a top-level declaration defines and
+// returns every name it declares
+func (info *moduleInfo) addDeclaration(declaration *sitter.Node, content []byte, isDefault bool, line int) []string {
+	var names []string
+
+	switch declaration.Type() {
+	case "function_declaration", "generator_function_declaration", "function_expression", "function":
+		name := anonymousDefault
+		if nameNode := declaration.ChildByFieldName("name"); nameNode != nil {
+			name = nodeText(nameNode, content)
+		}
+		if name != anonymousDefault || isDefault {
+			info.functions[name] = declaration
+			names = append(names, name)
+		}
+	case "lexical_declaration", "variable_declaration":
+		for i := 0; i < int(declaration.NamedChildCount()); i++ {
+			declarator := declaration.NamedChild(i)
+			if declarator.Type() != "variable_declarator" {
+				continue
+			}
+			nameNode := declarator.ChildByFieldName("name")
+			if nameNode == nil || nameNode.Type() != "identifier" {
+				continue
+			}
+			name := nodeText(nameNode, content)
+			names = append(names, name)
+
+			if value := declarator.ChildByFieldName("value"); value != nil {
+				if value.Type() == "arrow_function" || value.Type() == "function_expression" {
+					info.functions[name] = value
+				}
+			}
+		}
+	case "class_declaration", "abstract_class_declaration", "interface_declaration", "type_alias_declaration", "enum_declaration":
+		if nameNode := declaration.ChildByFieldName("name"); nameNode != nil {
+			names = append(names, nodeText(nameNode, content))
+		}
+	}
+
+	return names
+}
+
+// fileImports returns the import bindings of the file containing a node
+func fileImports(node *sitter.Node, content []byte) []importBinding {
+	rootNode := node
+	for rootNode.Parent() != nil {
+		rootNode = rootNode.Parent()
+	}
+
+	var imports []importBinding
+	for i := 0; i < int(rootNode.NamedChildCount()); i++ {
+		statement := rootNode.NamedChild(i)
+		if statement.Type() == "import_statement" {
+			imports = append(imports, parseImport(statement, content, int(statement.StartPoint().Row)+1)...)
+		}
+	}
+	return imports
+}
+
+// findSymbolCalls returns every call inside the function that resolves to the
+// symbol through the file's imports, whatever local name it was imported as.
+// Calls of a same-named function from another module do not count.
+func findSymbolCalls(funcNode *sitter.Node, content []byte, filename string, symbol *ImportedSymbol) []occurrence {
+	direct := make(map[string]bool)     // import { name as local }, or import local for the default export
+	namespaces := make(map[string]bool) // import * as local
+	for _, binding := range fileImports(funcNode, content) {
+		if binding.Local == "" || !sameModule(filename, binding.Source, symbol.Module) {
+			continue
+		}
+		switch binding.Imported {
+		case symbol.Name:
+			direct[binding.Local] = true
+		case "*":
+			namespaces[binding.Local] = true
+		}
+	}
+
+	var occurrences []occurrence
+	if len(direct) == 0 && len(namespaces) == 0 {
+		return occurrences
+	}
+
+	var walk func(node *sitter.Node)
+	walk = func(node *sitter.Node) {
+		for i := 0; i < int(node.NamedChildCount()); i++ {
+			child := node.NamedChild(i)
+			if child.Type() == "call_expression" && callsSymbol(child, content, symbol, direct, namespaces) {
+				occurrences = append(occurrences, occurrence{
+					offset: child.StartByte(),
+					line:   child.StartPoint().Row + 1,
+				})
+			}
+			walk(child)
+		}
+	}
+	walk(funcNode)
+
+	return occurrences
+}
+
+// sameModule reports whether a specifier imported by a file names the module
+// of a symbol, either as written or, through -project path aliases, by
+// resolving to the same file
+func sameModule(filename string, source string, module string) bool {
+	if source == module {
+		return true
+	}
+	if activeProject == nil {
+		return false
+	}
+
+	sourcePath, ok := resolveModule(filename, source)
+	if !ok {
+		return false
+	}
+	modulePath, ok := resolveModule(filename, module)
+	return ok && sourcePath == modulePath
+}
+
+// callsSymbol reports whether a call expression calls one of the local names
+// bound to the symbol, directly or as a member of a namespace import
+func callsSymbol(call *sitter.Node, content []byte, symbol *ImportedSymbol, direct map[string]bool, namespaces map[string]bool) bool {
+	function := call.ChildByFieldName("function")
+	if function == nil {
+		return false
+	}
+
+	switch function.Type() {
+	case "identifier":
+		return direct[nodeText(function, content)]
+	case "member_expression":
+		object := function.ChildByFieldName("object")
+		property := function.ChildByFieldName("property")
+		return object != nil && property != nil && object.Type() == "identifier" &&
+			namespaces[nodeText(object, content)] && nodeText(property, content) == symbol.Name
+	}
+	return false
+}
+
+// stringLiteralValue returns the value of a string literal without its quotes
+func stringLiteralValue(node *sitter.Node, content []byte) string {
+	return strings.Trim(nodeText(node, content), "'\"`")
+}
+
+// Extensions tried, in order, when resolving a module specifier to a file
+var moduleExtensions = []string{".ts", ".tsx", ".d.ts", "/index.ts", "/index.tsx"}
+
+// resolveModule resolves a module specifier imported from a file to the path
+// of a TypeScript file. Bare specifiers can only be resolved through the
+// paths and baseUrl of the -project tsconfig.
+func resolveModule(fromFile string, source string) (string, bool) {
+	if !strings.HasPrefix(source, "./") && !strings.HasPrefix(source, "../") && source != "." && source != ".." {
+		if activeProject != nil {
+			return activeProject.resolveNonRelative(fromFile, source)
+		}
+		return "", false
+	}
+	return resolveModulePath(filepath.Join(filepath.Dir(fromFile), source))
+}
+
+// resolveModulePath finds the TypeScript file for a module path without extension
+func resolveModulePath(base string) (string, bool) {
+	// ESM-style imports name the compiled .js file
+	trimmed := strings.TrimSuffix(strings.TrimSuffix(base, ".js"), ".jsx")
+
+	candidates := []string{base}
+	for _, ext := range moduleExtensions {
+		candidates = append(candidates, trimmed+ext)
+	}
+
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() && isTypeScriptFile(candidate) {
+			return candidate, true
+		}
+	}
+
+	return "", false
+}
+
+// isTypeScriptFile reports whether a path has a TypeScript extension
+func isTypeScriptFile(path string) bool {
+	return strings.HasSuffix(path, ".ts") || strings.HasSuffix(path, ".tsx")
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestImportRule(t *testing.T) {
+	rootNode, content := parseSource(t, `
+import { getContext as gc } from '@core/ctx';
+import * as ctx from '@core/ctx';
+import { getContext } from './local-ctx';
+import { helper } from '@core/ctx';
+
+export function aliased() {
+    const c = gc();
+    return c;
+}
+
+export function namespaced() {
+    return ctx.getContext().user;
+}
+
+export function otherModule() {
+    return getContext();
+}
+
+export function otherExport() {
+    return ctx.other() && helper();
+}
+
+export function mentionedOnly() {
+    // gc()
+    return "gc()";
+}
+`)
+
+	rule := &Rule{Import: &ImportedSymbol{Module: "@core/ctx", Name: "getContext"}}
+	if err := rule.validate(); err != nil {
+		t.Fatalf("Unexpected validation error: %v", err)
+	}
+
+	functions, err := selectFunctions(rootNode, "exported")
+	if err != nil {
+		t.Fatalf("Error selecting functions: %v", err)
+	}
+
+	var missing []string
+	for _, funcNode := range functions {
+		findings, err := rule.evaluate(funcNode, rootNode, content, "exported", "test.ts")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(findings) > 0 {
+			missing = append(missing, functionName(funcNode, content))
+		}
+	}
+
+	expected := []string{"otherModule", "otherExport", "mentionedOnly"}
+	if !reflect.DeepEqual(missing, expected) {
+		t.Errorf("Expected %v to be missing the call, got %v", expected, missing)
+	}
+
+	// Import patterns also work in order rules
+	order := &Rule{
+		Type:   ruleTypeOrder,
+		Before: &Pattern{Import: &ImportedSymbol{Module: "@core/ctx", Name: "getContext"}},
+		After:  &Pattern{Text: ".user"},
+	}
+	if err := order.validate(); err != nil {
+		t.Fatalf("Unexpected validation error: %v", err)
+	}
+	for _, funcNode := range functions {
+		findings, err := order.evaluate(funcNode, rootNode, content, "exported", "test.ts")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(findings) > 0 {
+			t.Errorf("Unexpected order finding in %s: %v", functionName(funcNode, content), findings)
+		}
+	}
+
+	invalid := []*Rule{
+		{Import: &ImportedSymbol{Module: "@core/ctx"}},
+		{CodeBlock: "getContext()", Import: &ImportedSymbol{Module: "@core/ctx", Name: "getContext"}},
+		{Type: ruleTypeOrder, Before: &Pattern{Text: "a(", Import: &ImportedSymbol{Module: "m", Name: "a"}}, After: &Pattern{Text: "b("}},
+	}
+	for i, rule := range invalid {
+		if err := rule.validate(); err == nil {
+			t.Errorf("Expected invalid rule %d to be rejected", i)
+		}
+	}
+}
+
+func TestParseModuleInfo(t *testing.T) {
+	rootNode, content := parseSource(t, `
+import def, { a as b, c } from './x';
+import * as ns from "./y";
+import './polyfill';
+
+export function f() {}
+export const g = () => {};
+export { f as h, g };
+export { z } from './z';
+export * from './all';
+export default function () {}
+`)
+
+	info := parseModuleInfo(rootNode, content)
+
+	imports := []importBinding{
+		{Local: "def", Imported: "default", Source: "./x", Line: 2},
+		{Local: "b", Imported: "a", Source: "./x", Line: 2},
+		{Local: "c", Imported: "c", Source: "./x", Line: 2},
+		{Local: "ns", Imported: "*", Source: "./y", Line: 3},
+		{Source: "./polyfill", Line: 4},
+	}
+	if !reflect.DeepEqual(info.imports, imports) {
+		t.Errorf("Expected imports %+v, got %+v", imports, info.imports)
+	}
+
+	exports := []exportBinding{
+		{Exported: "f", Local: "f", Line: 6},
+		{Exported: "g", Local: "g", Line: 7},
+		{Exported: "h", Local: "f", Line: 8},
+		{Exported: "g", Local: "g", Line: 8},
+		{Exported: "z", Local: "z", Source: "./z", Line: 9},
+		{Exported: "*", Local: "*", Source: "./all", Line: 10},
+		{Exported: "default", Local: anonymousDefault, Line: 11},
+	}
+	if !reflect.DeepEqual(info.exports, exports) {
+		t.Errorf("Expected exports %+v, got %+v", exports, info.exports)
+	}
+
+	for _, name := range []string{"f", "g", anonymousDefault} {
+		if info.functions[name] == nil {
+			t.Errorf("Expected function %q to be recorded", name)
+		}
+	}
+}
+
+func TestExportResolver(t *testing.T) {
+	tempDir := t.TempDir()
+	writeFiles(t, tempDir, map[string]string{
+		"index.ts": `import { a } from './a';
+export { a };
+export * from './b';
+export * as ns from './c';
+export { loop } from './loop';
+`,
+		"a.ts":     "export function a() {}\n",
+		"b.ts":     "export function b() {}\nexport const b2 = 1;\n",
+		"c.ts":     "export function c() {}\n",
+		"loop.ts":  "export { loop } from './loop2';\n",
+		"loop2.ts": "export { loop } from './loop';\n",
+	})
+
+	resolver := exportResolver{module: func(path string) *moduleInfo {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		rootNode, _ := parseSource(t, string(content))
+		return parseModuleInfo(rootNode, content)
+	}}
+	resolve := func(name string) []string {
+		var reached []string
+		resolver.resolve(filepath.Join(tempDir, "index.ts"), name, func(path string, local string) bool {
+			reached = append(reached, filepath.Base(path)+":"+local)
+			return false
+		})
+		return reached
+	}
+
+	tests := map[string][]string{
+		"a":    {"a.ts:a"},
+		"b2":   {"b.ts:b2"},
+		"ns":   {"c.ts:*", "c.ts:c"},
+		"loop": nil,
+		"*":    {"index.ts:*", "a.ts:a", "b.ts:*", "b.ts:b", "b.ts:b2", "c.ts:*", "c.ts:c"},
+	}
+	for name, expected := range tests {
+		if got := resolve(name); !reflect.DeepEqual(got, expected) {
+			t.Errorf("%s: expected %v, got %v", name, expected, got)
+		}
+	}
+
+	// Resolving stops at the first declaration visit accepts
+	found := resolver.resolve(filepath.Join(tempDir, "index.ts"), "*", func(path string, local string) bool {
+		return local == "a"
+	})
+	if !found {
+		t.Error("Expected resolving to stop at a")
+	}
+}
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"gopkg.in/yaml.v3"
+)
+
+// Rule name that disable and enable use for every rule
+const allRules = "*"
+
+// Override changes the rules of the files matching its globs. A rule whose
+// name is already used reconfigures that rule, keeping the options it does
+// not set; any other rule is added.
+type Override struct {
+	Files   globList    `yaml:"files"`
+	Rules   []yaml.Node `yaml:"rules"`
+	Disable []string    `yaml:"disable"`
+	Enable  []string    `yaml:"enable"`
+
+	// Directory the globs and the paths in rules are relative to, absolute
+	dir string
+
+	// Where the override is written, for error messages
+	origin string
+}
+
+// ruleChange changes the rules of a file: an override, or the config file of
+// a directory containing it
+type ruleChange interface {
+	apply(entries []ruleEntry) ([]ruleEntry, error)
+}
+
+// ruleEntry is a rule of a file while overrides are applied to it
+type ruleEntry struct {
+	rule     *Rule
+	disabled bool
+}
+
+// ruleSet is the rules that overrides give some files, with their cache
+type ruleSet struct {
+	rules []*Rule
+	cache *resultCache
+}
+
+// ruleResolver works out the rules of each file from the rules of the run,
+// the overrides matching it and the config files in the directories between
+// the checked directory and the file, such as those of workspace packages
+type ruleResolver struct {
+	root    string              // Checked directory, absolute
+	loaded  map[string]bool     // Config files already loaded with -config
+	configs map[string]*Config  // By directory, nil when it has none
+	sets    map[string]*ruleSet // By the base rules and the changes applied
+}
+
+// newRuleResolver creates a resolver for the files under root. The config
+// files in loaded are never read again as the config of their directory.
+func newRuleResolver(root string, loaded []string) *ruleResolver {
+	resolver := &ruleResolver{
+		root:    root,
+		loaded:  make(map[string]bool),
+		configs: make(map[string]*Config),
+		sets:    make(map[string]*ruleSet),
+	}
+	for _, path := range loaded {
+		if absPath, err := filepath.Abs(path); err == nil {
+			resolver.loaded[absPath] = true
+		}
+	}
+	return resolver
+}
+
+// validateOverrides checks the overrides of a config file and records where
+// they are written
+func validateOverrides(overrides []*Override, path string) error {
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+
+	for i, override := range overrides {
+		override.dir = dir
+		override.origin = fmt.Sprintf("%s: override %d", path, i+1)
+
+		if len(override.Files) == 0 {
+			return fmt.Errorf("%s: files is required", override.origin)
+		}
+		if err := override.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate checks the globs and the shape of the rules of an override
+func (o *Override) validate() error {
+	for _, pattern := range o.Files {
+		if !doublestar.ValidatePattern(strings.TrimPrefix(pattern, "./")) {
+			return fmt.Errorf("%s: invalid glob %q", o.origin, pattern)
+		}
+	}
+	for i, node := range o.Rules {
+		if node.Kind != yaml.MappingNode {
+			return fmt.Errorf("%s: rule %d: expected a mapping", o.origin, i+1)
+		}
+	}
+	return nil
+}
+
+// matches reports whether the override applies to a file. An override
+// without globs applies to every file under its directory.
+func (o *Override) matches(path string) bool {
+	if len(o.Files) == 0 {
+		return true
+	}
+
+	relative, err := filepath.Rel(o.dir, path)
+	if err != nil || strings.HasPrefix(relative, "..") {
+		return false
+	}
+	relative = filepath.ToSlash(relative)
+	for _, pattern := range o.Files {
+		if globMatches(pattern, relative) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply returns the rules of a file after the override. Its rules only
+// reconfigure the rules it is applied to, not each other.
+func (o *Override) apply(entries []ruleEntry) ([]ruleEntry, error) {
+	inherited := len(entries)
+	entries = append([]ruleEntry(nil), entries...)
+
+	for i := range o.Rules {
+		node := &o.Rules[i]
+		var patch Rule
+		if err := node.Decode(&patch); err != nil {
+			return nil, fmt.Errorf("%s: rule %d: %w", o.origin, i+1, err)
+		}
+
+		index := -1
+		for j, entry := range entries[:inherited] {
+			if patch.Name != "" && entry.rule.Name == patch.Name {
+				index = j
+				break
+			}
+		}
+
+		var rule *Rule
+		if index >= 0 {
+			rule = entries[index].rule.reconfigure(node, &patch, o.dir)
+			entries[index].rule = rule
+		} else {
+			rule = &patch
+			if err := resolveRulePaths(rule, o.dir); err != nil {
+				return nil, err
+			}
+			entries = append(entries, ruleEntry{rule: rule})
+		}
+
+		rule.origin = o.origin
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("%s: rule %d: %w", o.origin, i+1, err)
+		}
+	}
+
+	o.setDisabled(entries, o.Disable, true)
+	o.setDisabled(entries, o.Enable, false)
+	return entries, nil
+}
+
+// setDisabled disables or enables the rules with the given names. A name no
+// rule has is only logged, since the same config file can inherit different
+// rules depending on the -config of the run and the directories above it.
+func (o *Override) setDisabled(entries []ruleEntry, names []string, disabled bool) {
+	for _, name := range names {
+		found := false
+		for i := range entries {
+			if name == allRules || entries[i].rule.Name == name {
+				entries[i].disabled = disabled
+				found = true
+			}
+		}
+		if !found && name != allRules {
+			logger.Warn("no rule with this name to disable or enable", "config", o.origin, "name", name)
+		}
+	}
+}
+
+// reconfigure returns a copy of the rule with the options set in node taken
+// from patch. Paths set by the patch are relative to dir.
+func (r *Rule) reconfigure(node *yaml.Node, patch *Rule, dir string) *Rule {
+	rule := *r
+	// Compiled options are compiled again when the copy is validated
+	rule.codeBlock, rule.script, rule.plugin = nil, nil, nil
+	rule.nameRe, rule.paramTypeRe, rule.returnTypeRe, rule.condition = nil, nil, nil, nil
+
+	target, source := reflect.ValueOf(&rule).Elem(), reflect.ValueOf(patch).Elem()
+	fields := yamlFields(target.Type())
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if index, ok := fields[node.Content[i].Value]; ok {
+			target.FieldByIndex(index).Set(source.FieldByIndex(index))
+		}
+	}
+
+	if patch.Script != "" && !filepath.IsAbs(patch.Script) {
+		rule.Script = filepath.Join(dir, patch.Script)
+	}
+	if patch.Plugin != "" && !filepath.IsAbs(patch.Plugin) {
+		rule.Plugin = filepath.Join(dir, patch.Plugin)
+	}
+	if len(patch.From) > 0 || len(patch.Allow) > 0 || len(patch.Deny) > 0 {
+		rule.baseDir = dir
+	}
+	return &rule
+}
+
+// yamlFields returns the index of the fields of a struct type by YAML key,
+// including those of inlined structs
+func yamlFields(structType reflect.Type) map[string][]int {
+	fields := make(map[string][]int)
+
+	var collect func(structType reflect.Type, index []int)
+	collect = func(structType reflect.Type, index []int) {
+		for i := 0; i < structType.NumField(); i++ {
+			field := structType.Field(i)
+			tag := field.Tag.Get("yaml")
+			if !field.IsExported() || tag == "" || tag == "-" {
+				continue
+			}
+
+			fieldIndex := append(append([]int(nil), index...), i)
+			name, options, _ := strings.Cut(tag, ",")
+			if options == "inline" {
+				collect(field.Type, fieldIndex)
+				continue
+			}
+			fields[name] = fieldIndex
+		}
+	}
+	collect(structType, nil)
+
+	return fields
+}
+
+// rulesFor returns the rules a file is checked with, starting from the rules
+// and overrides of the config file that applies to it. It returns nil when
+// no override or nested config file changes them.
+func (r *ruleResolver) rulesFor(path string, rules []*Rule, overrides []*Override) (*ruleSet, error) {
+	var applied []ruleChange
+	key := fmt.Sprintf("%p", rules)
+
+	for _, override := range overrides {
+		if override.matches(path) {
+			applied = append(applied, override)
+			key += fmt.Sprintf(" %p", override)
+		}
+	}
+
+	for _, dir := range r.nestedDirs(path) {
+		config, err := r.configIn(dir)
+		if err != nil {
+			return nil, err
+		}
+		if config == nil {
+			continue
+		}
+
+		if config.Root {
+			rules, applied, key = nil, nil, "root"
+		}
+		applied = append(applied, config)
+		key += fmt.Sprintf(" %p", config)
+		for _, override := range config.Overrides {
+			if override.matches(path) {
+				applied = append(applied, override)
+				key += fmt.Sprintf(" %p", override)
+			}
+		}
+	}
+
+	if len(applied) == 0 {
+		return nil, nil
+	}
+	if set, ok := r.sets[key]; ok {
+		return set, nil
+	}
+
+	entries := make([]ruleEntry, len(rules))
+	for i, rule := range rules {
+		entries[i] = ruleEntry{rule: rule}
+	}
+	for _, change := range applied {
+		var err error
+		if entries, err = change.apply(entries); err != nil {
+			return nil, err
+		}
+	}
+
+	set := &ruleSet{rules: []*Rule{}}
+	for _, entry := range entries {
+		if !entry.disabled {
+			set.rules = append(set.rules, entry.rule)
+		}
+	}
+	r.sets[key] = set
+	return set, nil
+}
+
+// nestedDirs returns the directories whose config files apply to a file,
+// from the outermost: those below the checked directory containing it
+func (r *ruleResolver) nestedDirs(path string) []string {
+	relative, err := filepath.Rel(r.root, filepath.Dir(path))
+	if err != nil || relative == "." || strings.HasPrefix(relative, "..") {
+		return nil
+	}
+
+	var dirs []string
+	dir := r.root
+	for _, part := range strings.Split(relative, string(filepath.Separator)) {
+		dir = filepath.Join(dir, part)
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// configIn returns the config file of a directory, or nil when it has none
+func (r *ruleResolver) configIn(dir string) (*Config, error) {
+	if config, ok := r.configs[dir]; ok {
+		return config, nil
+	}
+
+	path := filepath.Join(dir, packageConfigName)
+	var config *Config
+	if !r.loaded[path] {
+		var err error
+		if config, err = loadDirectoryConfig(path); err != nil {
+			return nil, err
+		}
+	}
+	r.configs[dir] = config
+	return config, nil
+}
+
+// ruleSets returns every set of rules that overrides gave some file
+func (r *ruleResolver) ruleSets() []*ruleSet {
+	sets := make([]*ruleSet, 0, len(r.sets))
+	for _, set := range r.sets {
+		sets = append(sets, set)
+	}
+	return sets
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRuleOverrides(t *testing.T) {
+	tempDir := t.TempDir()
+	writeFiles(t, tempDir, map[string]string{
+		"config.yaml": `rules:
+  - name: context
+    code-block: getContext()
+  - name: logging
+    code-block: console.log(
+    invert: true
+overrides:
+  - files: src/repositories/**
+    rules:
+      - name: context
+        fn-types: exported,internal
+  - files: [src/scripts/**]
+    disable: ["*"]
+  - files: src/scripts/migrate.ts
+    enable: [logging]
+    rules:
+      - name: audit
+        code-block: audit(
+`,
+		"src/legacy/" + packageConfigName: `rules:
+  - name: context
+    severity: warning
+disable: [logging]
+`,
+		"src/legacy/v1/" + packageConfigName: `root: true
+rules:
+  - code-block: legacy(
+`,
+		"src/cli/" + packageConfigName: `extends: ts-analyzer:no-console
+disable: [logging, missing]
+plugins: []
+`,
+	})
+
+	config, err := loadConfig(filepath.Join(tempDir, "config.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	resolver := newRuleResolver(tempDir, []string{filepath.Join(tempDir, "config.yaml")})
+
+	describe := func(name string) string {
+		set, err := resolver.rulesFor(filepath.Join(tempDir, name), config.Rules, config.Overrides)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if set == nil {
+			return "unchanged"
+		}
+		var parts []string
+		for _, rule := range set.rules {
+			parts = append(parts, rule.Name+":"+rule.CodeBlock+":"+rule.FnTypes+":"+rule.Severity)
+		}
+		return strings.Join(parts, " ")
+	}
+
+	tests := map[string]string{
+		"src/index.ts":              "unchanged",
+		"src/repositories/users.ts": "context:getContext():exported,internal:error logging:console.log(:exported:error",
+		"src/scripts/seed.ts":       "",
+		"src/scripts/migrate.ts":    "logging:console.log(:exported:error audit:audit(:exported:error",
+		"src/legacy/old.ts":         "context:getContext():exported:warning",
+		"src/legacy/v1/older.ts":    ":legacy(:exported:error",
+		"src/cli/main.ts":           `context:getContext():exported:error no-console:\bconsole\.[a-z]+\(:exported:error`,
+	}
+	for name, expected := range tests {
+		if got := describe(name); got != expected {
+			t.Errorf("%s: expected rules %q, got %q", name, expected, got)
+		}
+	}
+
+	// Reconfigured rules are copies; the config's own rules are unchanged
+	if config.Rules[0].FnTypes != "exported" || config.Rules[0].Severity != severityError {
+		t.Errorf("Expected the base rule to be unchanged, got %+v", config.Rules[0])
+	}
+
+	// Files with the same overrides share their rules, and so their cache
+	first, _ := resolver.rulesFor(filepath.Join(tempDir, "src/repositories/a.ts"), config.Rules, config.Overrides)
+	second, _ := resolver.rulesFor(filepath.Join(tempDir, "src/repositories/b/c.ts"), config.Rules, config.Overrides)
+	if first != second {
+		t.Error("Expected files with the same overrides to share a rule set")
+	}
+}
+
+func TestRuleOverrideErrors(t *testing.T) {
+	tests := map[string]string{
+		"overrides:\n  - rules:\n      - code-block: a\n":          "files is required",
+		"overrides:\n  - files: 'src/[a'\n":                        "invalid glob",
+		"overrides:\n  - files: src\n    rules:\n      - a\n":      "expected a mapping",
+		"overrides:\n  - files: src\n    rules:\n      - min: 1\n": "code-block is required",
+	}
+
+	for content, expected := range tests {
+		tempDir := t.TempDir()
+		writeFiles(t, tempDir, map[string]string{"config.yaml": content})
+
+		config, err := loadConfig(filepath.Join(tempDir, "config.yaml"))
+		if err == nil {
+			resolver := newRuleResolver(tempDir, nil)
+			_, err = resolver.rulesFor(filepath.Join(tempDir, "src/index.ts"), config.Rules, config.Overrides)
+		}
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%q: expected an error containing %q, got %v", content, expected, err)
+		}
+	}
+}
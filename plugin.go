@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+// pluginRule is a WebAssembly module that checks functions. A plugin must
+// export its memory and two functions:
+//
+//	alloc(size i32) i32             reserves size bytes for the input and returns their address
+//	check(ptr i32, len i32) i64     checks the function described by the JSON input and
+//	                                returns the address and length of the JSON output
+//	                                packed as (ptr << 32) | len
+//
+// The input is a pluginInput and the output is a list of pluginFinding.
+type pluginRule struct {
+	path   string
+	module api.Module
+	alloc  api.Function
+	check  api.Function
+
+	// Module instances are not safe for concurrent use
+	mu sync.Mutex
+}
+
+// pluginInput is the JSON document passed to a plugin for each function
+type pluginInput struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	File      string `json:"file"`
+	StartByte uint32 `json:"start_byte"`
+	EndByte   uint32 `json:"end_byte"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Text      string `json:"text"`
+}
+
+// pluginFinding is a finding returned by a plugin. A line of 0 points at the function.
+type pluginFinding struct {
+	Line    uint32 `json:"line"`
+	Message string `json:"message"`
+}
+
+// pluginRuntime is shared by every plugin in the run
+var (
+	pluginRuntime     wazero.Runtime
+	pluginRuntimeOnce sync.Once
+	pluginRuntimeErr  error
+)
+
+// getPluginRuntime creates the WebAssembly runtime on first use. Plugins get
+// WASI without any preopened directories, so they cannot reach the file
+// system or network.
+func getPluginRuntime() (wazero.Runtime, error) {
+	pluginRuntimeOnce.Do(func() {
+		ctx := context.Background()
+		pluginRuntime = wazero.NewRuntime(ctx)
+		_, pluginRuntimeErr = wasi_snapshot_preview1.Instantiate(ctx, pluginRuntime)
+	})
+	return pluginRuntime, pluginRuntimeErr
+}
+
+// loadPluginRule compiles and instantiates a plugin once
+func loadPluginRule(path string) (*pluginRule, error) {
+	wasm, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	runtime, err := getPluginRuntime()
+	if err != nil {
+		return nil, fmt.Errorf("creating WebAssembly runtime: %w", err)
+	}
+
+	ctx := context.Background()
+	compiled, err := runtime.CompileModule(ctx, wasm)
+	if err != nil {
+		return nil, fmt.Errorf("compiling plugin %s: %w", path, err)
+	}
+
+	// Reactor modules built with WASI need _initialize; it is skipped when missing
+	config := wazero.NewModuleConfig().WithName("").WithStartFunctions("_initialize")
+	module, err := runtime.InstantiateModule(ctx, compiled, config)
+	if err != nil {
+		return nil, fmt.Errorf("instantiating plugin %s: %w", path, err)
+	}
+
+	plugin := &pluginRule{
+		path:   path,
+		module: module,
+		alloc:  module.ExportedFunction("alloc"),
+		check:  module.ExportedFunction("check"),
+	}
+	if plugin.alloc == nil || plugin.check == nil || module.Memory() == nil {
+		return nil, fmt.Errorf("plugin %s must export memory, alloc and check", path)
+	}
+
+	return plugin, nil
+}
+
+// run passes a single function to the plugin and returns its findings, or an
+// error when the plugin fails
+func (p *pluginRule) run(funcNode *sitter.Node, facts *FunctionFacts) ([]Finding, error) {
+	input, err := json.Marshal(pluginInput{
+		Kind:      facts.Kind,
+		Name:      facts.Name,
+		File:      facts.File,
+		StartByte: funcNode.StartByte(),
+		EndByte:   funcNode.EndByte(),
+		StartLine: facts.Line,
+		EndLine:   facts.EndLine,
+		Text:      facts.Text,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("line %d: encoding input of plugin %s: %w", facts.Line, p.path, err)
+	}
+
+	output, err := p.call(input)
+	if err != nil {
+		return nil, fmt.Errorf("line %d: running plugin %s: %w", facts.Line, p.path, err)
+	}
+
+	var results []pluginFinding
+	if err := json.Unmarshal(output, &results); err != nil {
+		return nil, fmt.Errorf("line %d: invalid output of plugin %s: %w", facts.Line, p.path, err)
+	}
+
+	logger.Debug("plugin reported findings", "file", facts.File, "line", facts.Line, "plugin", p.path, "findings", len(results))
+
+	var findings []Finding
+	for _, result := range results {
+		line := result.Line
+		if line == 0 {
+			line = uint32(facts.Line)
+		}
+		findings = append(findings, Finding{Line: line, Message: result.Message})
+	}
+
+	return findings, nil
+}
+
+// call copies the input into the plugin's memory and reads back its output
+func (p *pluginRule) call(input []byte) ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ctx := context.Background()
+
+	results, err := p.alloc.Call(ctx, uint64(len(input)))
+	if err != nil {
+		return nil, err
+	}
+	ptr := uint32(results[0])
+
+	if !p.module.Memory().Write(ptr, input) {
+		return nil, fmt.Errorf("alloc returned an address outside memory")
+	}
+
+	results, err = p.check.Call(ctx, uint64(ptr), uint64(len(input)))
+	if err != nil {
+		return nil, err
+	}
+
+	outPtr, outLen := uint32(results[0]>>32), uint32(results[0])
+	output, ok := p.module.Memory().Read(outPtr, outLen)
+	if !ok {
+		return nil, fmt.Errorf("check returned an output outside memory")
+	}
+
+	// Copy the output since the plugin may reuse its memory
+	return append([]byte(nil), output...), nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// testPluginWasm is a minimal plugin that reports every function it is given:
+//
+//	(module
+//	  (memory (export "memory") 1)
+//	  (func (export "alloc") (param i32) (result i32) (i32.const 1024))
+//	  (func (export "check") (param i32 i32) (result i64)
+//	    (i64.const 0x1000000002a)) ;; (256 << 32) | 42
+//	  (data (i32.const 256) "[{\"line\":0,\"message\":\"flagged by plugin\"}]"))
+var testPluginWasm = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00, 0x01, 0x0c, 0x02, 0x60,
+	0x01, 0x7f, 0x01, 0x7f, 0x60, 0x02, 0x7f, 0x7f, 0x01, 0x7e, 0x03, 0x03,
+	0x02, 0x00, 0x01, 0x05, 0x03, 0x01, 0x00, 0x01, 0x07, 0x1a, 0x03, 0x06,
+	0x6d, 0x65, 0x6d, 0x6f, 0x72, 0x79, 0x02, 0x00, 0x05, 0x61, 0x6c, 0x6c,
+	0x6f, 0x63, 0x00, 0x00, 0x05, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x00, 0x01,
+	0x0a, 0x11, 0x02, 0x05, 0x00, 0x41, 0x80, 0x08, 0x0b, 0x09, 0x00, 0x42,
+	0xaa, 0x80, 0x80, 0x80, 0x80, 0x20, 0x0b, 0x0b, 0x31, 0x01, 0x00, 0x41,
+	0x80, 0x02, 0x0b, 0x2a, 0x5b, 0x7b, 0x22, 0x6c, 0x69, 0x6e, 0x65, 0x22,
+	0x3a, 0x30, 0x2c, 0x22, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x22,
+	0x3a, 0x22, 0x66, 0x6c, 0x61, 0x67, 0x67, 0x65, 0x64, 0x20, 0x62, 0x79,
+	0x20, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x22, 0x7d, 0x5d,
+}
+
+func TestPluginRule(t *testing.T) {
+	tempDir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(tempDir, "flag.wasm"), testPluginWasm, 0644); err != nil {
+		t.Fatalf("Failed to write plugin: %v", err)
+	}
+
+	config := `
+plugins:
+  - plugin: flag.wasm
+    fn-name: ^legacy
+`
+	configPath := filepath.Join(tempDir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if len(cfg.Rules) != 1 || cfg.Rules[0].Type != ruleTypePlugin {
+		t.Fatalf("Expected a single plugin rule, got %+v", cfg.Rules)
+	}
+
+	rootNode, content := parseSource(t, `
+export function legacyHandler() {
+    return 1;
+}
+
+export function handler() {
+    return 2;
+}
+`)
+
+	functions, err := selectFunctions(rootNode, "exported")
+	if err != nil {
+		t.Fatalf("Error selecting functions: %v", err)
+	}
+
+	rule := cfg.Rules[0]
+	var findings []Finding
+	for _, funcNode := range functions {
+		selected, err := rule.selects(funcNode, rootNode, content, "exported", "test.ts")
+		if err != nil {
+			t.Fatalf("Unexpected error selecting functions: %v", err)
+		}
+		if !selected {
+			continue
+		}
+		ruleFindings, err := rule.evaluate(funcNode, rootNode, content, "exported", "test.ts")
+		if err != nil {
+			t.Fatalf("Unexpected error running the plugin: %v", err)
+		}
+		findings = append(findings, ruleFindings...)
+	}
+
+	expected := []Finding{{Line: 2, Message: "flagged by plugin"}}
+	if !reflect.DeepEqual(findings, expected) {
+		t.Errorf("Expected %v, got %v", expected, findings)
+	}
+}
+
+func TestLoadPluginRuleErrors(t *testing.T) {
+	tempDir := t.TempDir()
+
+	invalid := filepath.Join(tempDir, "invalid.wasm")
+	if err := os.WriteFile(invalid, []byte("not wasm"), 0644); err != nil {
+		t.Fatalf("Failed to write plugin: %v", err)
+	}
+	if _, err := loadPluginRule(invalid); err == nil {
+		t.Error("Expected an error loading an invalid module")
+	}
+
+	// A module without exports is valid WebAssembly but not a plugin
+	empty := filepath.Join(tempDir, "empty.wasm")
+	if err := os.WriteFile(empty, []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}, 0644); err != nil {
+		t.Fatalf("Failed to write plugin: %v", err)
+	}
+	if _, err := loadPluginRule(empty); err == nil {
+		t.Error("Expected an error loading a module without the plugin exports")
+	}
+}
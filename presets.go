@@ -0,0 +1,49 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Prefix of the built-in presets in extends, e.g. ts-analyzer:no-console
+const presetPrefix = "ts-analyzer:"
+
+// Built-in presets, one configuration file per preset
+//
+//go:embed presets/*.yaml
+var presetFiles embed.FS
+
+// isPreset reports whether an extends entry names a built-in preset
+func isPreset(name string) bool {
+	return strings.HasPrefix(name, presetPrefix)
+}
+
+// presetNames returns the names of the built-in presets, sorted
+func presetNames() []string {
+	entries, _ := fs.ReadDir(presetFiles, "presets")
+
+	var names []string
+	for _, entry := range entries {
+		names = append(names, presetPrefix+strings.TrimSuffix(entry.Name(), path.Ext(entry.Name())))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// readConfigSource returns the content of a configuration file or built-in preset
+func readConfigSource(name string) ([]byte, error) {
+	if !isPreset(name) {
+		return os.ReadFile(name)
+	}
+
+	data, err := presetFiles.ReadFile("presets/" + strings.TrimPrefix(name, presetPrefix) + ".yaml")
+	if err != nil {
+		return nil, fmt.Errorf("unknown preset %q, use one of %s", name, strings.Join(presetNames(), ", "))
+	}
+	return data, nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPresets(t *testing.T) {
+	expected := []string{"ts-analyzer:callback-try-catch", "ts-analyzer:no-console", "ts-analyzer:recommended"}
+	if names := presetNames(); !reflect.DeepEqual(names, expected) {
+		t.Fatalf("Expected presets %v, got %v", expected, names)
+	}
+
+	// Every preset loads on its own
+	for _, name := range expected {
+		if _, err := loadConfig(name); err != nil {
+			t.Errorf("Failed to load %s: %v", name, err)
+		}
+	}
+
+	content := []byte(`export function handler() {
+  console.log("handling");
+  [1, 2].forEach((n) => {
+    process(n);
+  });
+  [3].forEach((n) => {
+    try {
+      process(n);
+    } catch (e) {}
+  });
+}
+`)
+	config, err := loadConfig("ts-analyzer:recommended")
+	if err != nil {
+		t.Fatalf("Failed to load the recommended preset: %v", err)
+	}
+	result, err := analyzeContent(content, config.Rules, "handler.ts")
+	if err != nil {
+		t.Fatalf("Failed to analyze: %v", err)
+	}
+	findings := result.Findings
+
+	var messages []string
+	for _, finding := range findings {
+		messages = append(messages, finding.Message)
+	}
+	want := []string{"Contains forbidden code block", "Missing required code block"}
+	if !reflect.DeepEqual(messages, want) || findings[1].Line != 3 {
+		t.Errorf("Expected %v with the callback at line 3, got %v", want, findings)
+	}
+
+	if _, err := loadConfig("ts-analyzer:missing"); err == nil || !strings.Contains(err.Error(), "unknown preset") {
+		t.Errorf("Expected an unknown preset error, got %v", err)
+	}
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bmatcuk/doublestar/v4"
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/typescript/typescript"
+)
+
+// Function type of the functions in a package's public API
+const publicAPIFnType = "public-api"
+
+// Output directories mapped back to src when an entry point names a build output
+var buildOutputDirs = []string{"dist", "lib", "build", "out"}
+
+// packageAPI is the public API of a package: the local names, per file, of
+// everything reachable through re-export chains from its entry points, and
+// "*" for the files whose every export is
+type packageAPI struct {
+	dir     string
+	public  map[string]map[string]bool
+	modules map[string]*moduleInfo
+	parser  *sitter.Parser
+}
+
+// publicAPIIndex caches the public API of every package seen in the run
+var publicAPIIndex = struct {
+	sync.Mutex
+	packages map[string]*packageAPI // By package directory
+	dirs     map[string]string      // Package directory of each directory, "" for none
+}{
+	packages: make(map[string]*packageAPI),
+	dirs:     make(map[string]string),
+}
+
+// selectPublicFunctions returns the top-level functions of a file that are
+// part of its package's public API, in source order
+func selectPublicFunctions(rootNode *sitter.Node, content []byte, filename string) []*sitter.Node {
+	api := publicAPIFor(filename)
+	if api == nil {
+		return nil
+	}
+
+	var functions []*sitter.Node
+	for name, funcNode := range parseModuleInfo(rootNode, content).functions {
+		if api.public[filename][name] {
+			functions = append(functions, funcNode)
+		}
+	}
+
+	sort.Slice(functions, func(i, j int) bool {
+		return functions[i].StartByte() < functions[j].StartByte()
+	})
+	return functions
+}
+
+// publicAPIFor returns the public API of the package containing a file, or
+// nil when the file is not inside a package
+func publicAPIFor(filename string) *packageAPI {
+	publicAPIIndex.Lock()
+	defer publicAPIIndex.Unlock()
+
+	dir := packageDir(filepath.Dir(filename))
+	if dir == "" {
+		return nil
+	}
+
+	api, ok := publicAPIIndex.packages[dir]
+	if !ok {
+		api = loadPackageAPI(dir)
+		publicAPIIndex.packages[dir] = api
+	}
+	return api
+}
+
+// packageDir returns the nearest directory at or above dir with a package.json.
+// Must be called with publicAPIIndex locked.
+func packageDir(dir string) string {
+	if cached, ok := publicAPIIndex.dirs[dir]; ok {
+		return cached
+	}
+
+	var result string
+	if _, err := os.Stat(filepath.Join(dir, "package.json")); err == nil {
+		result = dir
+	} else if parent := filepath.Dir(dir); parent != dir {
+		result = packageDir(parent)
+	}
+
+	publicAPIIndex.dirs[dir] = result
+	return result
+}
+
+// loadPackageAPI follows every entry point of a package. A package.json that
+// cannot be read has an empty public API.
+func loadPackageAPI(dir string) *packageAPI {
+	api := &packageAPI{
+		dir:     dir,
+		public:  make(map[string]map[string]bool),
+		modules: make(map[string]*moduleInfo),
+		parser:  sitter.NewParser(),
+	}
+	api.parser.SetLanguage(typescript.GetLanguage())
+
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return api
+	}
+	var manifest map[string]json.RawMessage
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return api
+	}
+
+	resolver := exportResolver{module: api.module}
+	for _, entry := range packageEntryPoints(manifest) {
+		for _, file := range api.resolveEntryPoint(entry) {
+			resolver.resolve(file, "*", func(path string, local string) bool {
+				if api.public[path] == nil {
+					api.public[path] = make(map[string]bool)
+				}
+				api.public[path][local] = true
+				return false
+			})
+		}
+	}
+
+	return api
+}
+
+// packageEntryPoints returns the paths named by the main, module, types,
+// typings and exports fields of a package.json
+func packageEntryPoints(manifest map[string]json.RawMessage) []string {
+	var entries []string
+	for _, field := range []string{"main", "module", "types", "typings"} {
+		var entry string
+		if json.Unmarshal(manifest[field], &entry) == nil && entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+
+	// exports is a path, or nested subpath and condition maps and fallback lists
+	var collect func(raw json.RawMessage)
+	collect = func(raw json.RawMessage) {
+		var entry string
+		if json.Unmarshal(raw, &entry) == nil {
+			if entry != "" {
+				entries = append(entries, entry)
+			}
+			return
+		}
+		var list []json.RawMessage
+		if json.Unmarshal(raw, &list) == nil {
+			for _, item := range list {
+				collect(item)
+			}
+			return
+		}
+		var object map[string]json.RawMessage
+		if json.Unmarshal(raw, &object) == nil {
+			keys := make([]string, 0, len(object))
+			for key := range object {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				collect(object[key])
+			}
+		}
+	}
+	if exports, ok := manifest["exports"]; ok {
+		collect(exports)
+	}
+
+	return entries
+}
+
+// resolveEntryPoint returns the TypeScript sources of an entry point. Entry
+// points naming compiled output (dist/index.js, dist/index.d.ts) are mapped
+// to the source next to it or under src.
+func (a *packageAPI) resolveEntryPoint(entry string) []string {
+	entry = strings.TrimPrefix(filepath.ToSlash(entry), "./")
+
+	// A subpath pattern (./features/*.js) stands for every file under its prefix
+	if index := strings.Index(entry, "*"); index >= 0 {
+		for _, candidate := range entryCandidates(entry[:index]) {
+			matches, err := doublestar.FilepathGlob(filepath.ToSlash(filepath.Join(a.dir, candidate)) + "/**/*")
+			if err != nil {
+				continue
+			}
+			var files []string
+			for _, match := range matches {
+				if isTypeScriptFile(match) && !strings.HasSuffix(match, ".d.ts") {
+					files = append(files, match)
+				}
+			}
+			if len(files) > 0 {
+				return files
+			}
+		}
+		return nil
+	}
+
+	for _, candidate := range entryCandidates(strings.TrimSuffix(entry, ".d.ts")) {
+		if path, ok := resolveModulePath(filepath.Join(a.dir, candidate)); ok && !strings.HasSuffix(path, ".d.ts") {
+			return []string{path}
+		}
+	}
+	return nil
+}
+
+// entryCandidates returns the path of an entry point and, when it is inside a
+// build output directory, the same path under src
+func entryCandidates(relative string) []string {
+	candidates := []string{relative}
+	for _, outDir := range buildOutputDirs {
+		if rest, ok := strings.CutPrefix(relative, outDir+"/"); ok {
+			candidates = append(candidates, "src/"+rest)
+		}
+	}
+	return candidates
+}
+
+// module parses a file of the package once
+func (a *packageAPI) module(path string) *moduleInfo {
+	if info, ok := a.modules[path]; ok {
+		return info
+	}
+
+	var info *moduleInfo
+	if content, err := os.ReadFile(path); err == nil {
+		tree := a.parser.Parse(nil, content)
+		info = parseModuleInfo(tree.RootNode(), content)
+	}
+	a.modules[path] = info
+	return info
+}
+
+// publicAPIFingerprint hashes the package.json of every file's package and
+// the files themselves, since the public API depends on re-export chains
+func publicAPIFingerprint(files []string) ([]byte, error) {
+	paths := append([]string(nil), files...)
+
+	publicAPIIndex.Lock()
+	seen := make(map[string]bool)
+	for _, file := range files {
+		absPath, err := filepath.Abs(file)
+		if err != nil {
+			publicAPIIndex.Unlock()
+			return nil, err
+		}
+		if dir := packageDir(filepath.Dir(absPath)); dir != "" && !seen[dir] {
+			seen[dir] = true
+			paths = append(paths, filepath.Join(dir, "package.json"))
+		}
+	}
+	publicAPIIndex.Unlock()
+
+	return hashFiles(paths)
+}
+
+// hasPublicAPIRule reports whether any rule checks public-api functions
+func hasPublicAPIRule(rules []*Rule) bool {
+	for _, rule := range rules {
+		if parseFunctionTypes(rule.FnTypes)[publicAPIFnType] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPublicAPIFunctions(t *testing.T) {
+	tempDir := t.TempDir()
+	writeFiles(t, tempDir, map[string]string{
+		"package.json": `{
+			"name": "@acme/lib",
+			"main": "dist/index.js",
+			"exports": {
+				".": { "types": "./dist/index.d.ts", "import": "./dist/index.js" },
+				"./features/*": "./dist/features/*.js"
+			}
+		}`,
+		"src/index.ts": `import { d } from './d';
+export { a } from './a';
+export * from './b';
+export * as ns from './c';
+export { d };
+export default function () {}
+function notExported() {}
+`,
+		"src/a.ts":           "export function a() {}\nexport function aPrivate() {}\n",
+		"src/b.ts":           "export const b = () => {};\nfunction bInternal() {}\nexport { b2 };\nconst b2 = function () {};\n",
+		"src/c.ts":           "export function c1() {}\nexport function c2() {}\n",
+		"src/d.ts":           "export function d() {}\nexport function dHidden() {}\n",
+		"src/internal.ts":    "export function notPublic() {}\n",
+		"src/features/x.ts":  "export function feature() {}\n",
+		"other/package.json": `{"name": "other"}`,
+		"other/index.ts":     "export function noEntryPoints() {}\n",
+	})
+
+	expected := map[string][]string{
+		"src/index.ts":      {""},
+		"src/a.ts":          {"a"},
+		"src/b.ts":          {"b", "b2"},
+		"src/c.ts":          {"c1", "c2"},
+		"src/d.ts":          {"d"},
+		"src/internal.ts":   nil,
+		"src/features/x.ts": {"feature"},
+		"other/index.ts":    nil,
+	}
+
+	for name, want := range expected {
+		path := filepath.Join(tempDir, name)
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", name, err)
+		}
+		rootNode, _ := parseSource(t, string(content))
+
+		var got []string
+		for _, funcNode := range selectPublicFunctions(rootNode, content, path) {
+			got = append(got, functionName(funcNode, content))
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: expected public functions %q, got %q", name, want, got)
+		}
+	}
+
+	// Rules select public-api functions like any other function type
+	rule, err := compileCodeBlockRule("getContext()", false, false, publicAPIFnType)
+	if err != nil {
+		t.Fatalf("Failed to compile rule: %v", err)
+	}
+	path := filepath.Join(tempDir, "src/a.ts")
+	content, _ := os.ReadFile(path)
+	result, err := analyzeContent(content, []*Rule{rule}, path)
+	if err != nil {
+		t.Fatalf("Failed to analyze: %v", err)
+	}
+	findings := result.Findings
+	if len(findings) != 1 || findings[0].Line != 1 {
+		t.Errorf("Expected one finding for a, got %v", findings)
+	}
+
+	// A function that is both exported and public is checked once
+	rule, err = compileCodeBlockRule("getContext()", false, false, "exported,"+publicAPIFnType)
+	if err != nil {
+		t.Fatalf("Failed to compile rule: %v", err)
+	}
+	result, err = analyzeContent(content, []*Rule{rule}, path)
+	if err != nil {
+		t.Fatalf("Failed to analyze: %v", err)
+	}
+	var lines []uint32
+	for _, finding := range result.Findings {
+		lines = append(lines, finding.Line)
+	}
+	if !reflect.DeepEqual(lines, []uint32{1, 2}) {
+		t.Errorf("Expected one finding for each of a and aPrivate, got %v", result.Findings)
+	}
+}
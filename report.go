@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// jsonReport is the output of -json
+type jsonReport struct {
+	Status          string              `json:"status"`
+	FilesChecked    int                 `json:"files_checked"`
+	FilesWithIssues int                 `json:"files_with_issues"`
+	Issues          int                 `json:"issues"`
+	Severities      severityCounts      `json:"severities"`
+	Files           []jsonFileReport    `json:"files"`
+	Errors          []jsonErrorReport   `json:"errors,omitempty"`
+	SyntaxErrors    []jsonSyntaxError   `json:"syntax_errors,omitempty"`
+	Packages        []jsonPackageReport `json:"packages,omitempty"`
+}
+
+// jsonFileReport lists the findings of a file with issues
+type jsonFileReport struct {
+	Path     string    `json:"path"`
+	Package  string    `json:"package,omitempty"`
+	Findings []Finding `json:"findings"`
+}
+
+// jsonErrorReport is a file that could not be analyzed
+type jsonErrorReport struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// jsonSyntaxError is a file checked despite a syntax error, with where the
+// first error starts
+type jsonSyntaxError struct {
+	Path string `json:"path"`
+	syntaxError
+}
+
+// jsonPackageReport is the result of a workspace package. Status is "fail"
+// when any of its files has errors, so teams can gate on their own packages.
+type jsonPackageReport struct {
+	Name            string         `json:"name"`
+	Dir             string         `json:"dir"`
+	Status          string         `json:"status"`
+	FilesChecked    int            `json:"files_checked"`
+	FilesWithIssues int            `json:"files_with_issues"`
+	Issues          int            `json:"issues"`
+	Severities      severityCounts `json:"severities"`
+}
+
+// reportStatus returns the status of a file set in JSON reports
+func reportStatus(failed bool) string {
+	if failed {
+		return "fail"
+	}
+	return "pass"
+}
+
+// printJSONReport prints the findings of a run as JSON. Packages are only
+// reported in -workspaces mode. The status is "fail" when the findings fail
+// the run and "error" when any file could not be analyzed. Syntax errors are
+// warnings and do not change the status.
+func printJSONReport(filesChecked int, findings map[string][]Finding, analysisErrors map[string]error, syntaxErrors map[string]*syntaxError, packages []*workspacePackage, failed bool) error {
+	report := jsonReport{
+		Status:          reportStatus(failed),
+		FilesChecked:    filesChecked,
+		FilesWithIssues: len(findings),
+		Files:           []jsonFileReport{},
+	}
+
+	for path, fileFindings := range findings {
+		file := jsonFileReport{Path: path, Findings: fileFindings}
+		if pkg := packageFor(packages, path); pkg != nil {
+			file.Package = pkg.Name
+		}
+		if file.Findings == nil {
+			file.Findings = []Finding{}
+		}
+		report.Files = append(report.Files, file)
+		report.Issues += len(fileFindings)
+		report.Severities.add(fileFindings)
+	}
+	sort.Slice(report.Files, func(i, j int) bool {
+		return report.Files[i].Path < report.Files[j].Path
+	})
+
+	for path, err := range analysisErrors {
+		report.Errors = append(report.Errors, jsonErrorReport{Path: path, Message: err.Error()})
+	}
+	sort.Slice(report.Errors, func(i, j int) bool {
+		return report.Errors[i].Path < report.Errors[j].Path
+	})
+	if len(report.Errors) > 0 {
+		report.Status = "error"
+	}
+
+	for path, syntaxErr := range syntaxErrors {
+		report.SyntaxErrors = append(report.SyntaxErrors, jsonSyntaxError{Path: path, syntaxError: *syntaxErr})
+	}
+	sort.Slice(report.SyntaxErrors, func(i, j int) bool {
+		return report.SyntaxErrors[i].Path < report.SyntaxErrors[j].Path
+	})
+
+	for _, pkg := range packages {
+		report.Packages = append(report.Packages, jsonPackageReport{
+			Name:            pkg.Name,
+			Dir:             pkg.Dir,
+			Status:          reportStatus(pkg.severities.Errors > 0),
+			FilesChecked:    pkg.files,
+			FilesWithIssues: len(pkg.filesWithIssues),
+			Issues:          pkg.issues,
+			Severities:      pkg.severities,
+		})
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
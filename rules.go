@@ -0,0 +1,630 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/typescript/typescript"
+	"gopkg.in/yaml.v3"
+)
+
+// Rule types understood by the analyzer
+const (
+	ruleTypeCodeBlock = "code-block"
+	ruleTypeOrder     = "order"
+	ruleTypeScript    = "script"
+	ruleTypePlugin    = "plugin"
+	ruleTypeBoundary  = "import-boundary"
+	ruleTypeCycles    = "cycles"
+	ruleTypeUnused    = "unused-exports"
+)
+
+// Severities of the findings of a rule. Only errors fail the run.
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+	severityInfo    = "info"
+)
+
+// Pattern is a piece of code to look for inside a function. It is either
+// text (optionally a regular expression), a tree-sitter query, or calls of
+// an imported symbol.
+type Pattern struct {
+	Text   string          `yaml:"text"`
+	Regex  bool            `yaml:"regex"`
+	Query  string          `yaml:"query"`
+	Import *ImportedSymbol `yaml:"import"`
+
+	// Compiled Text or Query, set by validate
+	re    *regexp.Regexp
+	query *sitter.Query
+}
+
+// UnmarshalYAML allows a pattern to be written as a plain string
+func (p *Pattern) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		p.Text = value.Value
+		return nil
+	}
+
+	type plain Pattern
+	return value.Decode((*plain)(p))
+}
+
+// String returns the pattern as written in the configuration
+func (p *Pattern) String() string {
+	if p.Import != nil {
+		return p.Import.String()
+	}
+	if p.Query != "" {
+		return strings.Join(strings.Fields(p.Query), " ")
+	}
+	return p.Text
+}
+
+// Rule is a single check applied to every function of the selected types
+type Rule struct {
+	Name      string          `yaml:"name"`
+	Type      string          `yaml:"type"`
+	CodeBlock string          `yaml:"code-block"`
+	Import    *ImportedSymbol `yaml:"import"`
+	Regex     bool            `yaml:"regex"`
+	Invert    bool            `yaml:"invert"`
+	FnTypes   string          `yaml:"fn-types"`
+	Min       *int            `yaml:"min"`
+	Max       *int            `yaml:"max"`
+	Before    *Pattern        `yaml:"before"`
+	After     *Pattern        `yaml:"after"`
+	Script    string          `yaml:"script"`
+	Plugin    string          `yaml:"plugin"`
+	From      globList        `yaml:"from"`
+	Allow     globList        `yaml:"allow"`
+	Deny      globList        `yaml:"deny"`
+	Severity  string          `yaml:"severity"`
+
+	// Follow calls into helpers when the code block is missing
+	Transitive bool `yaml:"transitive"`
+	Depth      int  `yaml:"transitive-depth"`
+
+	FunctionFilter `yaml:",inline"`
+
+	// Compiled CodeBlock or Import, loaded Script and Plugin, set by validate
+	codeBlock *Pattern
+	script    *scriptRule
+	plugin    *pluginRule
+
+	// Call graph followed by transitive rules, set once the files are known
+	graph *callGraph
+
+	// Directory the globs of import-boundary rules are relative to
+	baseDir string
+
+	// Config file, and override in it, the rule was last set in
+	origin string
+
+	// Module graph searched by cycles rules, set once the files are known
+	modules *moduleGraph
+
+	// Imports of every file, used by unused-exports rules
+	usage *exportUsage
+}
+
+// FunctionFilter narrows the functions of the selected types a rule applies
+// to. Empty fields match every function.
+type FunctionFilter struct {
+	NamePattern string `yaml:"fn-name"`
+	Decorator   string `yaml:"fn-decorator"`
+	Async       bool   `yaml:"fn-async"`
+	ParamType   string `yaml:"fn-param-type"`
+	ReturnType  string `yaml:"fn-return-type"`
+	When        string `yaml:"when"`
+
+	// Compiled patterns and When expression, set by validate
+	nameRe       *regexp.Regexp
+	paramTypeRe  *regexp.Regexp
+	returnTypeRe *regexp.Regexp
+	condition    cel.Program
+}
+
+// Finding is a single violation reported for a function
+type Finding struct {
+	Line     uint32 `json:"line"`
+	Message  string `json:"message"`
+	Severity string `json:"severity"`
+}
+
+// occurrence is the position of a pattern match inside a file
+type occurrence struct {
+	offset uint32
+	line   uint32
+}
+
+// newCodeBlockRule creates the rule described by the -code-block flags
+func newCodeBlockRule(codeBlock string, isRegex bool, invert bool, fnTypes string) *Rule {
+	return &Rule{
+		Type:      ruleTypeCodeBlock,
+		CodeBlock: codeBlock,
+		Regex:     isRegex,
+		Invert:    invert,
+		FnTypes:   fnTypes,
+	}
+}
+
+// compileCodeBlockRule creates and validates a code block rule
+func compileCodeBlockRule(codeBlock string, isRegex bool, invert bool, fnTypes string) (*Rule, error) {
+	rule := newCodeBlockRule(codeBlock, isRegex, invert, fnTypes)
+	if err := rule.validate(); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// validate checks that the rule has everything its type needs and compiles
+// its patterns, so that a run fails up front on bad input and every file
+// shares the compiled form
+func (r *Rule) validate() error {
+	if r.Type == "" {
+		r.Type = ruleTypeCodeBlock
+	}
+	if r.FnTypes == "" {
+		r.FnTypes = "exported"
+	}
+	if len(parseFunctionTypes(r.FnTypes)) == 0 {
+		return fmt.Errorf("invalid function types %q", r.FnTypes)
+	}
+	if unknown := unknownFunctionTypes(r.FnTypes); len(unknown) > 0 {
+		return fmt.Errorf("unknown function types %s in %q, use 'exported', 'internal', 'callback' or 'public-api'", strings.Join(unknown, ", "), r.FnTypes)
+	}
+	if r.Severity == "" {
+		r.Severity = severityError
+	}
+	if !isSeverity(r.Severity) {
+		return fmt.Errorf("invalid severity %q, use 'error', 'warning' or 'info'", r.Severity)
+	}
+
+	if err := r.FunctionFilter.validate(); err != nil {
+		return err
+	}
+
+	switch r.Type {
+	case ruleTypeCodeBlock:
+		if r.CodeBlock != "" && r.Import != nil {
+			return fmt.Errorf("code-block and import cannot be combined")
+		}
+		if r.Import != nil {
+			r.codeBlock = &Pattern{Import: r.Import}
+		} else {
+			if r.CodeBlock == "" {
+				return fmt.Errorf("code-block is required")
+			}
+			r.codeBlock = &Pattern{Text: r.CodeBlock, Regex: r.Regex}
+		}
+		if err := r.codeBlock.validate("code-block"); err != nil {
+			return err
+		}
+		if r.Min != nil || r.Max != nil {
+			if r.Invert {
+				return fmt.Errorf("min and max cannot be combined with invert")
+			}
+			if (r.Min != nil && *r.Min < 0) || (r.Max != nil && *r.Max < 0) {
+				return fmt.Errorf("min and max must not be negative")
+			}
+			if r.Min != nil && r.Max != nil && *r.Min > *r.Max {
+				return fmt.Errorf("min %d is greater than max %d", *r.Min, *r.Max)
+			}
+		}
+		if r.Transitive {
+			if r.Invert || r.Min != nil || r.Max != nil {
+				return fmt.Errorf("transitive cannot be combined with invert, min or max")
+			}
+			if r.Depth < 0 {
+				return fmt.Errorf("transitive-depth must not be negative")
+			}
+			if r.Depth == 0 {
+				r.Depth = defaultTransitiveDepth
+			}
+		}
+	case ruleTypeOrder:
+		if err := r.Before.validate("before"); err != nil {
+			return err
+		}
+		if err := r.After.validate("after"); err != nil {
+			return err
+		}
+	case ruleTypeScript:
+		if r.Script == "" {
+			return fmt.Errorf("script is required")
+		}
+		script, err := loadScriptRule(r.Script)
+		if err != nil {
+			return err
+		}
+		r.script = script
+	case ruleTypePlugin:
+		if r.Plugin == "" {
+			return fmt.Errorf("plugin is required")
+		}
+		plugin, err := loadPluginRule(r.Plugin)
+		if err != nil {
+			return err
+		}
+		r.plugin = plugin
+	case ruleTypeBoundary:
+		if err := r.validateBoundary(); err != nil {
+			return err
+		}
+	case ruleTypeCycles, ruleTypeUnused:
+	default:
+		return fmt.Errorf("unknown rule type %q", r.Type)
+	}
+
+	return nil
+}
+
+// fileLevel reports whether the rule checks whole files rather than functions
+func (r *Rule) fileLevel() bool {
+	return r.Type == ruleTypeBoundary || r.Type == ruleTypeCycles || r.Type == ruleTypeUnused
+}
+
+// isSeverity reports whether name is a known severity
+func isSeverity(name string) bool {
+	return name == severityError || name == severityWarning || name == severityInfo
+}
+
+// withSeverity returns copies of findings carrying the rule's severity. The
+// findings are copied since file-level rules may share them between rules.
+func (r *Rule) withSeverity(findings []Finding) []Finding {
+	if len(findings) == 0 {
+		return findings
+	}
+
+	severity := r.Severity
+	if severity == "" {
+		severity = severityError
+	}
+
+	result := make([]Finding, len(findings))
+	for i, finding := range findings {
+		finding.Severity = severity
+		result[i] = finding
+	}
+	return result
+}
+
+// checkFile applies a file-level rule
+func (r *Rule) checkFile(rootNode *sitter.Node, content []byte, filename string) ([]Finding, error) {
+	switch r.Type {
+	case ruleTypeCycles:
+		return r.checkCycles(filename), nil
+	case ruleTypeUnused:
+		return r.checkUnusedExports(rootNode, content, filename)
+	}
+	return r.checkImports(rootNode, content, filename), nil
+}
+
+// validate checks that exactly one of text, query or import is set and compiles it
+func (p *Pattern) validate(field string) error {
+	if p == nil {
+		return fmt.Errorf("%s pattern is required", field)
+	}
+
+	set := 0
+	for _, ok := range []bool{p.Text != "", p.Query != "", p.Import != nil} {
+		if ok {
+			set++
+		}
+	}
+	if set == 0 {
+		return fmt.Errorf("%s pattern is required", field)
+	}
+	if set > 1 {
+		return fmt.Errorf("%s pattern must use only one of text, query or import", field)
+	}
+
+	if p.Import != nil {
+		if p.Import.Module == "" || p.Import.Name == "" {
+			return fmt.Errorf("%s import needs both module and name", field)
+		}
+	} else if p.Query != "" {
+		query, err := sitter.NewQuery([]byte(p.Query), typescript.GetLanguage())
+		if err != nil {
+			return fmt.Errorf("invalid %s query: %w", field, err)
+		}
+		if query.CaptureCount() == 0 {
+			return fmt.Errorf("%s query needs a capture, such as @match, to locate its matches", field)
+		}
+		p.query = query
+	} else if p.Regex {
+		re, err := regexp.Compile(p.Text)
+		if err != nil {
+			return fmt.Errorf("invalid %s regex: %w", field, err)
+		}
+		p.re = re
+	}
+
+	return nil
+}
+
+// validate compiles the filter's patterns
+func (f *FunctionFilter) validate() error {
+	var err error
+	if f.NamePattern != "" {
+		if f.nameRe, err = regexp.Compile(f.NamePattern); err != nil {
+			return fmt.Errorf("invalid fn-name pattern: %w", err)
+		}
+	}
+	if f.ParamType != "" {
+		if f.paramTypeRe, err = regexp.Compile(anchored(f.ParamType)); err != nil {
+			return fmt.Errorf("invalid fn-param-type pattern: %w", err)
+		}
+	}
+	if f.ReturnType != "" {
+		if f.returnTypeRe, err = regexp.Compile(anchored(f.ReturnType)); err != nil {
+			return fmt.Errorf("invalid fn-return-type pattern: %w", err)
+		}
+	}
+	if f.When != "" {
+		program, err := compileCondition(f.When)
+		if err != nil {
+			return fmt.Errorf("invalid when expression: %w", err)
+		}
+		f.condition = program
+	}
+	return nil
+}
+
+// anchored makes a pattern match the whole string
+func anchored(pattern string) string {
+	return "^(?:" + pattern + ")$"
+}
+
+// selects reports whether the function of the given type passes every
+// filter, or an error when its when expression fails
+func (f *FunctionFilter) selects(funcNode *sitter.Node, rootNode *sitter.Node, content []byte, fnType string, filename string) (bool, error) {
+	if f.Async && !isAsyncFunction(funcNode) {
+		return false, nil
+	}
+
+	if f.nameRe != nil {
+		if !f.nameRe.MatchString(functionName(funcNode, content)) {
+			return false, nil
+		}
+	}
+
+	if f.Decorator != "" {
+		found := false
+		for _, decorator := range functionDecorators(funcNode, content) {
+			if decorator == normalizeDecorator(f.Decorator) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false, nil
+		}
+	}
+
+	if f.paramTypeRe != nil {
+		found := false
+		for _, paramType := range functionParamTypes(funcNode, content) {
+			if f.paramTypeRe.MatchString(paramType) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false, nil
+		}
+	}
+
+	if f.returnTypeRe != nil {
+		if !f.returnTypeRe.MatchString(functionReturnType(funcNode, content)) {
+			return false, nil
+		}
+	}
+
+	if f.condition != nil {
+		facts := newFunctionFacts(funcNode, rootNode, content, fnType, filename)
+		selected, err := evalCondition(f.condition, facts)
+		if err != nil {
+			return false, fmt.Errorf("line %d: evaluating when expression: %w", facts.Line, err)
+		}
+		return selected, nil
+	}
+
+	return true, nil
+}
+
+// evaluate applies the rule to a single function of the given type and
+// returns its findings, or an error when a script or plugin fails
+func (r *Rule) evaluate(funcNode *sitter.Node, rootNode *sitter.Node, content []byte, fnType string, filename string) ([]Finding, error) {
+	var finding *Finding
+
+	switch r.Type {
+	case ruleTypeOrder:
+		finding = checkOrder(funcNode, content, filename, r.Before, r.After)
+	case ruleTypeScript:
+		findings, err := r.script.check(funcNode, newFunctionFacts(funcNode, rootNode, content, fnType, filename), content)
+		if err != nil && r.Name != "" {
+			return nil, fmt.Errorf("rule %s: %w", r.Name, err)
+		}
+		return findings, err
+	case ruleTypePlugin:
+		return r.plugin.run(funcNode, newFunctionFacts(funcNode, rootNode, content, fnType, filename))
+	default:
+		if r.Min != nil || r.Max != nil {
+			finding = r.checkCount(funcNode, content, filename)
+			break
+		}
+
+		hasCodeBlock := r.usesCodeBlock(funcNode, content, filename)
+		line := funcNode.StartPoint().Row + 1
+
+		// If inverted, we want functions that DON'T have the code block
+		// If not inverted, we want functions that DO have the code block
+		if r.Invert && hasCodeBlock {
+			finding = &Finding{Line: line, Message: "Contains forbidden code block"}
+		}
+		if !r.Invert && !hasCodeBlock {
+			if r.Transitive {
+				finding = r.checkTransitive(funcNode, content, filename)
+				break
+			}
+			finding = &Finding{Line: line, Message: "Missing required code block"}
+		}
+	}
+
+	if finding == nil {
+		return nil, nil
+	}
+	return []Finding{*finding}, nil
+}
+
+// usesCodeBlock reports whether the function contains the rule's code block
+func (r *Rule) usesCodeBlock(funcNode *sitter.Node, content []byte, filename string) bool {
+	if r.codeBlock.Import != nil {
+		found := len(findSymbolCalls(funcNode, content, filename, r.codeBlock.Import)) > 0
+		logger.Debug("looking for calls", "file", filename, "symbol", r.codeBlock.Import.String(), "found", found)
+		return found
+	}
+
+	funcContent := string(content[funcNode.StartByte():funcNode.EndByte()])
+	return codeBlockUsed(funcContent, r.CodeBlock, r.codeBlock.re)
+}
+
+// checkCount reports a function whose number of code block occurrences is
+// outside the rule's min and max
+func (r *Rule) checkCount(funcNode *sitter.Node, content []byte, filename string) *Finding {
+	count := len(findOccurrences(funcNode, content, filename, r.codeBlock))
+	line := funcNode.StartPoint().Row + 1
+
+	logger.Debug("counted code block occurrences", "file", filename, "line", line, "code_block", r.codeBlock.String(), "count", count)
+
+	var expected string
+	switch {
+	case r.Min != nil && r.Max != nil && *r.Min == *r.Max:
+		if count != *r.Min {
+			expected = fmt.Sprintf("exactly %d", *r.Min)
+		}
+	case r.Min != nil && count < *r.Min:
+		expected = fmt.Sprintf("at least %d", *r.Min)
+	case r.Max != nil && count > *r.Max:
+		expected = fmt.Sprintf("at most %d", *r.Max)
+	}
+
+	if expected == "" {
+		return nil
+	}
+
+	return &Finding{
+		Line:    line,
+		Message: fmt.Sprintf("Found %d occurrence(s) of code block, expected %s", count, expected),
+	}
+}
+
+// checkOrder reports the first occurrence of after that is not preceded by an
+// occurrence of before inside the function
+func checkOrder(funcNode *sitter.Node, content []byte, filename string, before *Pattern, after *Pattern) *Finding {
+	afterOccurrences := findOccurrences(funcNode, content, filename, after)
+	if len(afterOccurrences) == 0 {
+		return nil
+	}
+
+	beforeOccurrences := findOccurrences(funcNode, content, filename, before)
+
+	first := afterOccurrences[0]
+	if len(beforeOccurrences) > 0 && beforeOccurrences[0].offset < first.offset {
+		return nil
+	}
+
+	logger.Debug("pattern found out of order", "file", filename, "line", first.line, "after", after.String(), "before", before.String())
+
+	return &Finding{
+		Line:    first.line,
+		Message: fmt.Sprintf("%q must come after %q", after.String(), before.String()),
+	}
+}
+
+// findOccurrences returns every position of the pattern inside the function,
+// sorted by offset. Text matches on comment lines are ignored.
+func findOccurrences(funcNode *sitter.Node, content []byte, filename string, p *Pattern) []occurrence {
+	var occurrences []occurrence
+
+	if p.Import != nil {
+		occurrences = findSymbolCalls(funcNode, content, filename, p.Import)
+	} else if p.query != nil {
+		cursor := sitter.NewQueryCursor()
+		cursor.Exec(p.query, funcNode)
+
+		for {
+			match, ok := cursor.NextMatch()
+			if !ok {
+				break
+			}
+			// Predicates such as #eq? are only checked when filtering
+			match = cursor.FilterPredicates(match, content)
+			if len(match.Captures) == 0 {
+				continue
+			}
+
+			node := match.Captures[0].Node
+			occurrences = append(occurrences, occurrence{
+				offset: node.StartByte(),
+				line:   node.StartPoint().Row + 1,
+			})
+		}
+	} else {
+		offset := funcNode.StartByte()
+		line := funcNode.StartPoint().Row + 1
+		funcContent := string(content[funcNode.StartByte():funcNode.EndByte()])
+
+		for _, text := range strings.Split(funcContent, "\n") {
+			// Skip comment lines
+			trimmedLine := strings.TrimSpace(text)
+			if !strings.HasPrefix(trimmedLine, "//") && !strings.HasPrefix(trimmedLine, "/*") {
+				for _, index := range findAllInLine(text, p.Text, p.re) {
+					occurrences = append(occurrences, occurrence{
+						offset: offset + uint32(index),
+						line:   line,
+					})
+				}
+			}
+
+			offset += uint32(len(text)) + 1
+			line++
+		}
+	}
+
+	sort.Slice(occurrences, func(i, j int) bool {
+		return occurrences[i].offset < occurrences[j].offset
+	})
+
+	return occurrences
+}
+
+// findAllInLine returns the start index of every match of text (or re, when set) in line
+func findAllInLine(line string, text string, re *regexp.Regexp) []int {
+	var indexes []int
+
+	if re != nil {
+		for _, loc := range re.FindAllStringIndex(line, -1) {
+			indexes = append(indexes, loc[0])
+		}
+		return indexes
+	}
+
+	for start := 0; start <= len(line); {
+		index := strings.Index(line[start:], text)
+		if index < 0 {
+			break
+		}
+		indexes = append(indexes, start+index)
+		start += index + len(text)
+	}
+
+	return indexes
+}
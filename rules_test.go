@@ -0,0 +1,331 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/typescript/typescript"
+)
+
+// parseSource parses TypeScript source for tests
+func parseSource(t testing.TB, source string) (*sitter.Node, []byte) {
+	t.Helper()
+
+	content := []byte(source)
+	parser := sitter.NewParser()
+	parser.SetLanguage(typescript.GetLanguage())
+
+	tree := parser.Parse(nil, content)
+	return tree.RootNode(), content
+}
+
+func TestOrderRule(t *testing.T) {
+	rootNode, content := parseSource(t, `
+export function ordered() {
+    authorize(user);
+    return db.find();
+}
+
+export function unordered() {
+    const rows = db.find();
+    authorize(user);
+    return rows;
+}
+
+export function missing() {
+    // authorize(user);
+    return db.find();
+}
+
+export function unrelated() {
+    return 1;
+}
+
+export function logged() {
+    log(user);
+    return db.find();
+}
+`)
+
+	testCases := []struct {
+		name  string
+		rule  *Rule
+		lines []uint32
+	}{
+		{
+			name: "Text patterns",
+			rule: &Rule{
+				Type:   ruleTypeOrder,
+				Before: &Pattern{Text: "authorize("},
+				After:  &Pattern{Text: "db."},
+			},
+			lines: []uint32{8, 15, 24},
+		},
+		{
+			name: "Structural pattern",
+			rule: &Rule{
+				Type:   ruleTypeOrder,
+				Before: &Pattern{Query: `(call_expression function: (identifier) @fn (#eq? @fn "authorize"))`},
+				After:  &Pattern{Text: `db\.\w+\(`, Regex: true},
+			},
+			lines: []uint32{8, 15, 24},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.rule.validate(); err != nil {
+				t.Fatalf("Unexpected validation error: %v", err)
+			}
+
+			functions, err := selectFunctions(rootNode, "exported")
+			if err != nil {
+				t.Fatalf("Error selecting functions: %v", err)
+			}
+
+			var lines []uint32
+			for _, funcNode := range functions {
+				findings, err := tc.rule.evaluate(funcNode, rootNode, content, "exported", "test.ts")
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				for _, finding := range findings {
+					lines = append(lines, finding.Line)
+				}
+			}
+
+			if len(lines) != len(tc.lines) {
+				t.Fatalf("Expected findings at lines %v, got %v", tc.lines, lines)
+			}
+			for i := range lines {
+				if lines[i] != tc.lines[i] {
+					t.Errorf("Expected findings at lines %v, got %v", tc.lines, lines)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testCases := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{
+			name: "Order rule with plain string patterns",
+			content: `
+rules:
+  - type: order
+    fn-types: exported,internal
+    before: authorize(
+    after:
+      text: db\.
+      regex: true
+`,
+		},
+		{
+			name: "Order rule missing a pattern",
+			content: `
+rules:
+  - type: order
+    before: authorize(
+`,
+			wantErr: true,
+		},
+		{
+			name: "Pattern with both text and query",
+			content: `
+rules:
+  - type: order
+    before: authorize(
+    after:
+      text: db.
+      query: (call_expression) @call
+`,
+			wantErr: true,
+		},
+		{
+			name: "Unknown rule type",
+			content: `
+rules:
+  - type: sometimes
+`,
+			wantErr: true,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(tempDir, filepath.Base(t.Name())+".yaml")
+			if err := os.WriteFile(path, []byte(tc.content), 0644); err != nil {
+				t.Fatalf("Failed to write config: %v", err)
+			}
+
+			config, err := loadConfig(path)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("Test case %d: expected an error", i)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Test case %d: unexpected error: %v", i, err)
+			}
+			if len(config.Rules) != 1 || config.Rules[0].Before.Text != "authorize(" || !config.Rules[0].After.Regex {
+				t.Errorf("Test case %d: unexpected rules %+v", i, config.Rules)
+			}
+		})
+	}
+}
+
+func TestCountRule(t *testing.T) {
+	rootNode, content := parseSource(t, `
+export function once() {
+    const ctx = getContext();
+    return ctx;
+}
+
+export function twice() {
+    const a = getContext(); const b = getContext();
+    return [a, b];
+}
+
+export function commented() {
+    // const ctx = getContext();
+    return null;
+}
+`)
+
+	zero, one, two := 0, 1, 2
+
+	testCases := []struct {
+		name     string
+		min      *int
+		max      *int
+		messages []string
+	}{
+		{
+			name: "Exactly one",
+			min:  &one,
+			max:  &one,
+			messages: []string{
+				"Found 2 occurrence(s) of code block, expected exactly 1",
+				"Found 0 occurrence(s) of code block, expected exactly 1",
+			},
+		},
+		{
+			name:     "At most one",
+			max:      &one,
+			messages: []string{"Found 2 occurrence(s) of code block, expected at most 1"},
+		},
+		{
+			name:     "At least two",
+			min:      &two,
+			messages: []string{"Found 1 occurrence(s) of code block, expected at least 2", "Found 0 occurrence(s) of code block, expected at least 2"},
+		},
+		{
+			name: "At most zero",
+			max:  &zero,
+			messages: []string{
+				"Found 1 occurrence(s) of code block, expected at most 0",
+				"Found 2 occurrence(s) of code block, expected at most 0",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rule := newCodeBlockRule("getContext()", false, false, "exported")
+			rule.Min = tc.min
+			rule.Max = tc.max
+			if err := rule.validate(); err != nil {
+				t.Fatalf("Unexpected validation error: %v", err)
+			}
+
+			functions, err := selectFunctions(rootNode, "exported")
+			if err != nil {
+				t.Fatalf("Error selecting functions: %v", err)
+			}
+
+			var messages []string
+			for _, funcNode := range functions {
+				findings, err := rule.evaluate(funcNode, rootNode, content, "exported", "test.ts")
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				for _, finding := range findings {
+					messages = append(messages, finding.Message)
+				}
+			}
+
+			if len(messages) != len(tc.messages) {
+				t.Fatalf("Expected %q, got %q", tc.messages, messages)
+			}
+			for i := range messages {
+				if messages[i] != tc.messages[i] {
+					t.Errorf("Expected %q, got %q", tc.messages[i], messages[i])
+				}
+			}
+		})
+	}
+
+	invalid := newCodeBlockRule("getContext()", false, false, "exported")
+	invalid.Min = &two
+	invalid.Max = &one
+	if err := invalid.validate(); err == nil {
+		t.Error("Expected an error when min is greater than max")
+	}
+}
+
+func TestRuleValidateCompilesPatterns(t *testing.T) {
+	rule := newCodeBlockRule(`using \w+ = getContext\(\)`, true, false, "exported")
+	rule.NamePattern = "^handle"
+	if err := rule.validate(); err != nil {
+		t.Fatalf("Unexpected validation error: %v", err)
+	}
+	if rule.codeBlock.re == nil || rule.nameRe == nil {
+		t.Error("Expected validate to compile the code block and name patterns")
+	}
+
+	// The single code block check functions compile each rule once
+	first, err := codeBlockRule(`db\.\w+\(`, true, false, "exported")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if second, _ := codeBlockRule(`db\.\w+\(`, true, false, "exported"); second != first {
+		t.Error("Expected the same code block rule to be compiled once")
+	}
+	if other, _ := codeBlockRule(`db\.\w+\(`, true, true, "exported"); other == first {
+		t.Error("Expected an inverted rule to be compiled separately")
+	}
+
+	order := &Rule{
+		Type:   ruleTypeOrder,
+		Before: &Pattern{Query: `(call_expression) @call`},
+		After:  &Pattern{Text: "db."},
+	}
+	if err := order.validate(); err != nil {
+		t.Fatalf("Unexpected validation error: %v", err)
+	}
+	if order.Before.query == nil {
+		t.Error("Expected validate to compile the before query")
+	}
+
+	invalid := []*Rule{
+		newCodeBlockRule("using (", true, false, "exported"),
+		{Type: ruleTypeOrder, Before: &Pattern{Query: "(call_expression"}, After: &Pattern{Text: "db."}},
+		{Type: ruleTypeOrder, Before: &Pattern{Query: "(call_expression)"}, After: &Pattern{Text: "db."}},
+		{Type: ruleTypeOrder, Before: &Pattern{Text: "a("}, After: &Pattern{Text: "b(", Regex: true}},
+		{Type: ruleTypeCodeBlock, CodeBlock: "x", FunctionFilter: FunctionFilter{ReturnType: "Promise<("}},
+	}
+	for i, rule := range invalid {
+		if err := rule.validate(); err == nil {
+			t.Errorf("Test case %d: expected a validation error", i)
+		}
+	}
+}
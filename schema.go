@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"unicode"
+)
+
+// Descriptions of the configuration keys shown by editors, by YAML key or,
+// for keys whose meaning depends on where they are, by definition and key
+var schemaDescriptions = map[string]string{
+	"override.rules":      "Rules that reconfigure the rule with the same name, or are added",
+	"importedSymbol.name": "Name of the imported symbol",
+	"extends":             "Configuration files, directories of them or built-in presets (ts-analyzer:...) merged before this file",
+	"rules":               "Rules applied to every function of the selected types. In a subdirectory, a rule with the name of an inherited rule reconfigures it",
+	"plugins":             "Rules of type plugin",
+	"overrides":           "Changes to the rules for the files matching some globs",
+	"files":               "Globs of the files the override applies to, relative to the configuration file",
+	"disable":             "Names of the rules to disable, or \"*\" for every rule",
+	"enable":              "Names of the rules to enable again, or \"*\" for every rule",
+	"root":                "Do not inherit rules from the directories above",
+	"name":                "Name of the rule, used to reconfigure, disable or enable it",
+	"type":                "Kind of check",
+	"code-block":          "Code that must exist in each function, or must not with invert",
+	"import":              "Calls of an imported symbol to look for instead of a code block",
+	"regex":               "Treat the code block as a regular expression",
+	"invert":              "Report functions that contain the code block",
+	"fn-types":            "Comma-separated function types: exported, internal, callback, public-api",
+	"min":                 "Minimum number of code block occurrences per function",
+	"max":                 "Maximum number of code block occurrences per function",
+	"before":              "Code that must come first in order rules",
+	"after":               "Code that must come after the before pattern in order rules",
+	"script":              "Starlark script of a script rule, relative to the configuration file",
+	"plugin":              "WebAssembly module of a plugin rule, relative to the configuration file",
+	"from":                "Globs of the files an import-boundary rule applies to",
+	"allow":               "Globs of the modules that may be imported",
+	"deny":                "Globs of the modules that must not be imported",
+	"severity":            "Severity of the findings; only errors fail the run",
+	"transitive":          "Accept functions whose every chain of calls reaches the code block",
+	"transitive-depth":    "Maximum number of calls followed by transitive",
+	"fn-name":             "Only check functions whose name matches this regular expression",
+	"fn-decorator":        "Only check methods with this decorator, on the method or its class",
+	"fn-async":            "Only check async functions",
+	"fn-param-type":       "Only check functions with a parameter whose type matches this regular expression",
+	"fn-return-type":      "Only check functions whose return type matches this regular expression",
+	"when":                "Only check functions for which this CEL expression is true",
+	"text":                "Text to look for",
+	"query":               "Tree-sitter query to look for",
+	"module":              "Module the symbol is imported from",
+}
+
+// Values allowed for some keys
+var schemaEnums = map[string][]string{
+	"type":     {ruleTypeCodeBlock, ruleTypeOrder, ruleTypeScript, ruleTypePlugin, ruleTypeBoundary, ruleTypeCycles, ruleTypeUnused},
+	"severity": {severityError, severityWarning, severityInfo},
+}
+
+// Comma-separated function types, as parseFunctionTypes accepts them
+const fnTypesSchemaPattern = `^\s*(exported|internal|callback|public-api)\s*(,\s*(exported|internal|callback|public-api)\s*)*$`
+
+// runSchema implements the schema subcommand and returns the exit code
+func runSchema(args []string) int {
+	flags := flag.NewFlagSet("schema", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return exitUsageError
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(configSchema(reflect.TypeOf(configFile{}))); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing schema: %v\n", err)
+		return exitAnalysisError
+	}
+	return exitClean
+}
+
+// configSchema returns the JSON Schema of a configuration file decoded into
+// the root type. It is built from the same fields that decodeStrict accepts.
+func configSchema(root reflect.Type) map[string]any {
+	definitions := make(map[string]any)
+	schema := objectSchema(root, definitions)
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	schema["title"] = "ts-analyzer configuration"
+	schema["definitions"] = definitions
+	return schema
+}
+
+// typeSchema returns the schema of the values decoded into a type. Structs
+// are added to definitions and referenced.
+func typeSchema(t reflect.Type, definitions map[string]any) map[string]any {
+	stringSchema := map[string]any{"type": "string"}
+
+	switch t {
+	case reflect.TypeOf(globList{}), reflect.TypeOf(stringList{}):
+		return map[string]any{"oneOf": []any{stringSchema, map[string]any{"type": "array", "items": stringSchema}}}
+	case yamlNodeType:
+		return typeSchema(reflect.TypeOf(Rule{}), definitions)
+	case reflect.TypeOf(Pattern{}):
+		// Patterns can be written as plain text
+		return map[string]any{"oneOf": []any{stringSchema, definitionSchema(t, definitions)}}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return typeSchema(t.Elem(), definitions)
+	case reflect.String:
+		return stringSchema
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem(), definitions)}
+	case reflect.Struct:
+		return definitionSchema(t, definitions)
+	}
+	return map[string]any{}
+}
+
+// definitionSchema adds a struct to definitions and returns a reference to it
+func definitionSchema(t reflect.Type, definitions map[string]any) map[string]any {
+	name := definitionName(t)
+	if _, ok := definitions[name]; !ok {
+		definitions[name] = nil // Added before its fields, which may refer to it
+		definitions[name] = objectSchema(t, definitions)
+	}
+	return map[string]any{"$ref": "#/definitions/" + name}
+}
+
+// definitionName returns the name of a struct in definitions, e.g. importedSymbol
+func definitionName(t reflect.Type) string {
+	name := []rune(t.Name())
+	name[0] = unicode.ToLower(name[0])
+	return string(name)
+}
+
+// objectSchema returns the schema of a struct decoded from a mapping
+func objectSchema(t reflect.Type, definitions map[string]any) map[string]any {
+	properties := make(map[string]any)
+	for key, index := range yamlFields(t) {
+		property := typeSchema(t.FieldByIndex(index).Type, definitions)
+		if _, ok := property["$ref"]; ok {
+			// Keywords next to $ref are ignored
+			property = map[string]any{"allOf": []any{property}}
+		}
+		if description, ok := schemaDescriptions[definitionName(t)+"."+key]; ok {
+			property["description"] = description
+		} else if description, ok := schemaDescriptions[key]; ok {
+			property["description"] = description
+		}
+		if values, ok := schemaEnums[key]; ok && t == reflect.TypeOf(Rule{}) {
+			property["enum"] = values
+		}
+		if key == "fn-types" {
+			property["pattern"] = fnTypesSchemaPattern
+		}
+		properties[key] = property
+	}
+
+	return map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+}
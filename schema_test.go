@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestConfigSchema(t *testing.T) {
+	schema := configSchema(reflect.TypeOf(configFile{}))
+	if _, err := json.Marshal(schema); err != nil {
+		t.Fatalf("Failed to encode the schema: %v", err)
+	}
+
+	definitions := schema["definitions"].(map[string]any)
+	rule, ok := definitions["rule"].(map[string]any)
+	if !ok {
+		t.Fatalf("Expected a rule definition, got %v", definitions)
+	}
+	if rule["additionalProperties"] != false {
+		t.Error("Expected unknown rule keys to be rejected")
+	}
+
+	// Every key decodeStrict accepts is in the schema, including inlined filters
+	properties := rule["properties"].(map[string]any)
+	for key := range yamlFields(reflect.TypeOf(Rule{})) {
+		if _, ok := properties[key]; !ok {
+			t.Errorf("Expected rule key %q in the schema", key)
+		}
+	}
+
+	severity := properties["severity"].(map[string]any)
+	if !reflect.DeepEqual(severity["enum"], []string{severityError, severityWarning, severityInfo}) {
+		t.Errorf("Expected the severities to be listed, got %v", severity)
+	}
+	before := properties["before"].(map[string]any)
+	if _, ok := before["oneOf"]; !ok {
+		t.Errorf("Expected patterns to accept plain strings, got %v", before)
+	}
+
+	// Config files in subdirectories use the same format
+	for _, key := range []string{"root", "extends", "disable", "enable"} {
+		if _, ok := schema["properties"].(map[string]any)[key]; !ok {
+			t.Errorf("Expected %s in the schema", key)
+		}
+	}
+}
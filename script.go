@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"go.starlark.net/starlark"
+)
+
+// scriptRule is a Starlark script that defines a check(fn) function. The
+// script reports findings by calling report(message, node=None).
+type scriptRule struct {
+	path string
+	fn   *starlark.Function
+}
+
+// Key of the thread-local findings list used by report
+const scriptFindingsKey = "findings"
+
+// Maximum number of Starlark steps a script may take to load, or to check a
+// single function. A check takes a few thousand steps even for large
+// functions, so reaching this means the script loops forever or nearly so,
+// and the run fails instead of hanging.
+const maxScriptSteps = 10_000_000
+
+// scriptBuiltins are the names predeclared for every rule script
+var scriptBuiltins = starlark.StringDict{
+	"report": starlark.NewBuiltin("report", scriptReport),
+}
+
+// loadScriptRule executes a rule script once and keeps its check function
+func loadScriptRule(path string) (*scriptRule, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	thread := &starlark.Thread{Name: path}
+	thread.SetMaxExecutionSteps(maxScriptSteps)
+	globals, err := starlark.ExecFile(thread, path, src, scriptBuiltins)
+	if err != nil {
+		return nil, fmt.Errorf("loading script %s: %w", path, err)
+	}
+	globals.Freeze()
+
+	check, ok := globals["check"].(*starlark.Function)
+	if !ok {
+		return nil, fmt.Errorf("script %s must define a check(fn) function", path)
+	}
+
+	return &scriptRule{path: path, fn: check}, nil
+}
+
+// check runs the script against a single function and returns what it
+// reported, or an error when the script fails
+func (s *scriptRule) check(funcNode *sitter.Node, facts *FunctionFacts, content []byte) ([]Finding, error) {
+	thread := &starlark.Thread{Name: s.path}
+	thread.SetMaxExecutionSteps(maxScriptSteps)
+	findings := []Finding{}
+	thread.SetLocal(scriptFindingsKey, &findings)
+
+	fn := &scriptNode{node: funcNode, content: content, facts: facts}
+	if _, err := starlark.Call(thread, s.fn, starlark.Tuple{fn}, nil); err != nil {
+		return nil, fmt.Errorf("line %d: running script %s: %w", facts.Line, s.path, err)
+	}
+
+	// Findings reported without a node point at the function
+	for i := range findings {
+		if findings[i].Line == 0 {
+			findings[i].Line = uint32(facts.Line)
+		}
+	}
+
+	logger.Debug("script reported findings", "file", facts.File, "line", facts.Line, "script", s.path, "findings", len(findings))
+
+	return findings, nil
+}
+
+// scriptReport implements report(message, node=None)
+func scriptReport(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var message string
+	var node starlark.Value = starlark.None
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "message", &message, "node?", &node); err != nil {
+		return nil, err
+	}
+
+	findings, ok := thread.Local(scriptFindingsKey).(*[]Finding)
+	if !ok {
+		return nil, fmt.Errorf("%s: can only be called while checking a function", b.Name())
+	}
+
+	var line uint32
+	switch n := node.(type) {
+	case *scriptNode:
+		line = n.node.StartPoint().Row + 1
+	case starlark.NoneType:
+	default:
+		return nil, fmt.Errorf("%s: node must be a syntax node, got %s", b.Name(), node.Type())
+	}
+
+	*findings = append(*findings, Finding{Line: line, Message: message})
+	return starlark.None, nil
+}
+
+// scriptNode is the read-only view of a syntax node given to scripts. The
+// function being checked also carries its facts.
+type scriptNode struct {
+	node    *sitter.Node
+	content []byte
+	facts   *FunctionFacts
+}
+
+var _ starlark.HasAttrs = (*scriptNode)(nil)
+
+func (n *scriptNode) String() string {
+	return fmt.Sprintf("<node %s:%d>", n.node.Type(), n.node.StartPoint().Row+1)
+}
+
+func (n *scriptNode) Type() string         { return "node" }
+func (n *scriptNode) Freeze()              {}
+func (n *scriptNode) Truth() starlark.Bool { return starlark.True }
+
+func (n *scriptNode) Hash() (uint32, error) {
+	return n.node.StartByte() ^ n.node.EndByte()<<16, nil
+}
+
+// Attr returns the node attributes and methods available to scripts
+func (n *scriptNode) Attr(name string) (starlark.Value, error) {
+	switch name {
+	case "type":
+		return starlark.String(n.node.Type()), nil
+	case "text":
+		return starlark.String(nodeText(n.node, n.content)), nil
+	case "line":
+		return starlark.MakeInt(int(n.node.StartPoint().Row) + 1), nil
+	case "end_line":
+		return starlark.MakeInt(int(n.node.EndPoint().Row) + 1), nil
+	case "children":
+		children := make([]starlark.Value, 0, n.node.NamedChildCount())
+		for i := 0; i < int(n.node.NamedChildCount()); i++ {
+			children = append(children, n.wrap(n.node.NamedChild(i)))
+		}
+		list := starlark.NewList(children)
+		list.Freeze()
+		return list, nil
+	case "field":
+		return starlark.NewBuiltin("field", n.field), nil
+	case "find":
+		return starlark.NewBuiltin("find", n.find), nil
+	}
+
+	if n.facts != nil {
+		switch name {
+		case "name":
+			return starlark.String(n.facts.Name), nil
+		case "kind":
+			return starlark.String(n.facts.Kind), nil
+		case "exported":
+			return starlark.Bool(n.facts.Exported), nil
+		case "async":
+			return starlark.Bool(n.facts.Async), nil
+		case "class":
+			return starlark.String(n.facts.Class), nil
+		}
+	}
+
+	return nil, nil
+}
+
+// AttrNames lists the attributes returned by Attr
+func (n *scriptNode) AttrNames() []string {
+	names := []string{"children", "end_line", "field", "find", "line", "text", "type"}
+	if n.facts != nil {
+		names = append(names, "async", "class", "exported", "kind", "name")
+	}
+	return names
+}
+
+// wrap creates the view of another node in the same file
+func (n *scriptNode) wrap(node *sitter.Node) *scriptNode {
+	return &scriptNode{node: node, content: n.content}
+}
+
+// field implements node.field(name), returning the child with that field name or None
+func (n *scriptNode) field(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var name string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &name); err != nil {
+		return nil, err
+	}
+
+	child := n.node.ChildByFieldName(name)
+	if child == nil {
+		return starlark.None, nil
+	}
+	return n.wrap(child), nil
+}
+
+// find implements node.find(type), returning every descendant of that type in source order
+func (n *scriptNode) find(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var nodeType string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &nodeType); err != nil {
+		return nil, err
+	}
+
+	var found []starlark.Value
+	var walk func(node *sitter.Node)
+	walk = func(node *sitter.Node) {
+		for i := 0; i < int(node.NamedChildCount()); i++ {
+			child := node.NamedChild(i)
+			if child.Type() == nodeType {
+				found = append(found, n.wrap(child))
+			}
+			walk(child)
+		}
+	}
+	walk(n.node)
+
+	list := starlark.NewList(found)
+	list.Freeze()
+	return list, nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScriptRule(t *testing.T) {
+	tempDir := t.TempDir()
+
+	script := `
+def check(fn):
+    params = fn.field("parameters")
+    takes_tx = params != None and [p for p in params.children if p.field("pattern").text == "tx"]
+    if not takes_tx:
+        return
+    for call in fn.find("call_expression"):
+        if call.field("function").text.startswith("db."):
+            report("%s takes tx but calls %s directly" % (fn.name, call.field("function").text), call)
+`
+	if err := os.WriteFile(filepath.Join(tempDir, "tx.star"), []byte(script), 0644); err != nil {
+		t.Fatalf("Failed to write script: %v", err)
+	}
+
+	config := `
+rules:
+  - type: script
+    script: tx.star
+    fn-types: exported,internal
+`
+	configPath := filepath.Join(tempDir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	rootNode, content := parseSource(t, `
+export function save(tx: Transaction, user: User) {
+    tx.insert(user);
+    db.insert(user);
+}
+
+export function load(id: string) {
+    return db.find(id);
+}
+`)
+
+	functions, err := selectFunctions(rootNode, "exported")
+	if err != nil {
+		t.Fatalf("Error selecting functions: %v", err)
+	}
+
+	var findings []Finding
+	for _, funcNode := range functions {
+		ruleFindings, err := cfg.Rules[0].evaluate(funcNode, rootNode, content, "exported", "test.ts")
+		if err != nil {
+			t.Fatalf("Unexpected error running the script: %v", err)
+		}
+		findings = append(findings, ruleFindings...)
+	}
+
+	expected := []Finding{{Line: 4, Message: "save takes tx but calls db.insert directly"}}
+	if !reflect.DeepEqual(findings, expected) {
+		t.Errorf("Expected %v, got %v", expected, findings)
+	}
+}
+
+func TestLoadScriptRuleErrors(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testCases := map[string]string{
+		"missing_check.star": "def other(fn):\n    pass\n",
+		"syntax_error.star":  "def check(fn)\n",
+	}
+
+	for name, script := range testCases {
+		path := filepath.Join(tempDir, name)
+		if err := os.WriteFile(path, []byte(script), 0644); err != nil {
+			t.Fatalf("Failed to write script: %v", err)
+		}
+		if _, err := loadScriptRule(path); err == nil {
+			t.Errorf("Expected an error loading %s", name)
+		}
+	}
+
+	if _, err := loadScriptRule(filepath.Join(tempDir, "missing.star")); err == nil {
+		t.Error("Expected an error loading a missing script")
+	}
+}
+
+func TestScriptStepLimit(t *testing.T) {
+	tempDir := t.TempDir()
+	writeFiles(t, tempDir, map[string]string{
+		"spin.star":    "def check(fn):\n    for i in range(1000000000):\n        pass\n",
+		"loading.star": "x = len([1 for i in range(1000000000) if False])\ndef check(fn):\n    pass\n",
+	})
+
+	if _, err := loadScriptRule(filepath.Join(tempDir, "loading.star")); err == nil || !strings.Contains(err.Error(), "too many steps") {
+		t.Errorf("Expected loading a script that runs too long to fail, got %v", err)
+	}
+
+	rule := &Rule{Name: "spin", Type: ruleTypeScript, Script: filepath.Join(tempDir, "spin.star")}
+	if err := rule.validate(); err != nil {
+		t.Fatalf("Unexpected validation error: %v", err)
+	}
+	rootNode, content := parseSource(t, "export function a() {}\n")
+	functions, err := selectFunctions(rootNode, "exported")
+	if err != nil {
+		t.Fatalf("Error selecting functions: %v", err)
+	}
+
+	_, err = rule.evaluate(functions[0], rootNode, content, "exported", "test.ts")
+	if err == nil || !strings.Contains(err.Error(), "rule spin: line 1: running script") || !strings.Contains(err.Error(), "too many steps") {
+		t.Errorf("Expected the rule to fail with too many steps, got %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import "fmt"
+
+// severityCounts counts the findings of each severity
+type severityCounts struct {
+	Errors   int `json:"error"`
+	Warnings int `json:"warning"`
+	Infos    int `json:"info"`
+}
+
+// add counts findings. Findings without a severity are errors.
+func (c *severityCounts) add(findings []Finding) {
+	for _, finding := range findings {
+		switch finding.Severity {
+		case severityWarning:
+			c.Warnings++
+		case severityInfo:
+			c.Infos++
+		default:
+			c.Errors++
+		}
+	}
+}
+
+// total returns the number of findings counted
+func (c severityCounts) total() int {
+	return c.Errors + c.Warnings + c.Infos
+}
+
+// failed reports whether the findings fail a run: any error, or more
+// warnings than maxWarnings when it is not negative
+func (c severityCounts) failed(maxWarnings int) bool {
+	return c.Errors > 0 || (maxWarnings >= 0 && c.Warnings > maxWarnings)
+}
+
+// String describes the counts, e.g. "2 error(s), 1 warning(s), 0 info"
+func (c severityCounts) String() string {
+	return fmt.Sprintf("%d error(s), %d warning(s), %d info", c.Errors, c.Warnings, c.Infos)
+}
+
+// formatFinding formats a finding for the text output. Errors keep the
+// format used before findings had a severity.
+func formatFinding(filename string, finding Finding) string {
+	if finding.Severity == "" || finding.Severity == severityError {
+		return fmt.Sprintf("%s:%d - %s", filename, finding.Line, finding.Message)
+	}
+	return fmt.Sprintf("%s:%d - %s: %s", filename, finding.Line, finding.Severity, finding.Message)
+}
@@ -0,0 +1,85 @@
+package main
+
+import "testing"
+
+func TestSeverityCounts(t *testing.T) {
+	var counts severityCounts
+	counts.add([]Finding{
+		{Line: 1, Message: "a", Severity: severityError},
+		{Line: 2, Message: "b", Severity: severityWarning},
+		{Line: 3, Message: "c", Severity: severityWarning},
+		{Line: 4, Message: "d", Severity: severityInfo},
+		{Line: 5, Message: "e"},
+	})
+
+	if counts != (severityCounts{Errors: 2, Warnings: 2, Infos: 1}) {
+		t.Fatalf("Unexpected counts %+v", counts)
+	}
+	if counts.String() != "2 error(s), 2 warning(s), 1 info" {
+		t.Errorf("Unexpected summary %q", counts.String())
+	}
+
+	tests := []struct {
+		counts      severityCounts
+		maxWarnings int
+		failed      bool
+	}{
+		{severityCounts{}, -1, false},
+		{severityCounts{Errors: 1}, -1, true},
+		{severityCounts{Warnings: 5, Infos: 3}, -1, false},
+		{severityCounts{Warnings: 5}, 5, false},
+		{severityCounts{Warnings: 6}, 5, true},
+		{severityCounts{Warnings: 1}, 0, true},
+		{severityCounts{Infos: 10}, 0, false},
+	}
+	for _, test := range tests {
+		if failed := test.counts.failed(test.maxWarnings); failed != test.failed {
+			t.Errorf("%+v with -max-warnings %d: expected failed=%v", test.counts, test.maxWarnings, test.failed)
+		}
+	}
+}
+
+func TestFormatFinding(t *testing.T) {
+	tests := []struct {
+		finding  Finding
+		expected string
+	}{
+		{Finding{Line: 3, Message: "Missing required code block", Severity: severityError}, "a.ts:3 - Missing required code block"},
+		{Finding{Line: 3, Message: "Missing required code block"}, "a.ts:3 - Missing required code block"},
+		{Finding{Line: 7, Message: "Import cycle: a.ts -> a.ts", Severity: severityWarning}, "a.ts:7 - warning: Import cycle: a.ts -> a.ts"},
+		{Finding{Line: 1, Message: "Unused", Severity: severityInfo}, "a.ts:1 - info: Unused"},
+	}
+	for _, test := range tests {
+		if got := formatFinding("a.ts", test.finding); got != test.expected {
+			t.Errorf("Expected %q, got %q", test.expected, got)
+		}
+	}
+}
+
+func TestRuleSeverity(t *testing.T) {
+	rule := &Rule{CodeBlock: "authorize("}
+	if err := rule.validate(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if rule.Severity != severityError {
+		t.Errorf("Expected rules to default to %q, got %q", severityError, rule.Severity)
+	}
+
+	if err := (&Rule{CodeBlock: "authorize(", Severity: "fatal"}).validate(); err == nil {
+		t.Error("Expected an error for an unknown severity")
+	}
+
+	warning := &Rule{CodeBlock: "authorize(", Severity: severityWarning}
+	if err := warning.validate(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	content := []byte("export function handler() {\n  return 1;\n}\n")
+	result, err := analyzeContent(content, []*Rule{rule, warning}, "handler.ts")
+	if err != nil {
+		t.Fatalf("Failed to analyze: %v", err)
+	}
+	findings := result.Findings
+	if len(findings) != 2 || findings[0].Severity != severityError || findings[1].Severity != severityWarning {
+		t.Errorf("Expected one error and one warning, got %v", findings)
+	}
+}
@@ -0,0 +1,589 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// tsProject is a TypeScript project loaded from a tsconfig.json, with the
+// files tsc would compile and the options needed to resolve imports
+type tsProject struct {
+	configPath string
+	files      []string // Absolute and sorted
+	fileSet    map[string]bool
+
+	baseURL   string              // Absolute, or empty
+	paths     map[string][]string // compilerOptions.paths
+	pathsBase string              // Directory the paths targets are relative to
+
+	references []*tsProject
+
+	// Every config file read, including extended and referenced ones
+	configFiles []string
+}
+
+// tsconfigFile is the part of a tsconfig.json the analyzer understands
+type tsconfigFile struct {
+	Extends         json.RawMessage            `json:"extends"`
+	Files           *[]string                  `json:"files"`
+	Include         *[]string                  `json:"include"`
+	Exclude         *[]string                  `json:"exclude"`
+	References      []tsReference              `json:"references"`
+	CompilerOptions map[string]json.RawMessage `json:"compilerOptions"`
+}
+
+// tsReference is an entry of the references list
+type tsReference struct {
+	Path string `json:"path"`
+}
+
+// tsconfigResolved is a config with its extends chain applied. File lists
+// keep the directory of the config that set them, since their patterns are
+// relative to it.
+type tsconfigResolved struct {
+	files, include, exclude          *[]string
+	filesDir, includeDir, excludeDir string
+
+	baseURL, outDir string // Absolute
+	paths           map[string][]string
+	pathsDir        string
+
+	configFiles []string
+}
+
+// Directories tsc excludes when exclude is not set
+var defaultProjectExcludes = []string{"node_modules", "bower_components", "jspm_packages"}
+
+// Maximum length of an extends chain, to stop on cycles
+const maxExtendsDepth = 32
+
+// activeProject is the project given with -project. When set, imports are
+// resolved with its paths and baseUrl as well as relative to the importing file.
+var activeProject *tsProject
+
+// loadProject loads a tsconfig.json, or the tsconfig.json in a directory, and
+// the projects it references
+func loadProject(path string) (*tsProject, error) {
+	return loadProjectReferences(path, make(map[string]*tsProject))
+}
+
+func loadProjectReferences(path string, loaded map[string]*tsProject) (*tsProject, error) {
+	configPath, err := projectConfigPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if project, ok := loaded[configPath]; ok {
+		return project, nil
+	}
+
+	config, err := readTSConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := resolveTSConfig(configPath, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := resolved.projectFiles()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", configPath, err)
+	}
+
+	project := &tsProject{
+		configPath:  configPath,
+		files:       files,
+		fileSet:     make(map[string]bool, len(files)),
+		baseURL:     resolved.baseURL,
+		paths:       resolved.paths,
+		pathsBase:   resolved.pathsDir,
+		configFiles: resolved.configFiles,
+	}
+	for _, file := range files {
+		project.fileSet[file] = true
+	}
+	if project.baseURL != "" && project.paths != nil {
+		project.pathsBase = project.baseURL
+	}
+	loaded[configPath] = project
+
+	for _, reference := range config.References {
+		referencePath := reference.Path
+		if !filepath.IsAbs(referencePath) {
+			referencePath = filepath.Join(filepath.Dir(configPath), referencePath)
+		}
+		referenced, err := loadProjectReferences(referencePath, loaded)
+		if err != nil {
+			return nil, fmt.Errorf("%s: reference %s: %w", configPath, reference.Path, err)
+		}
+		project.references = append(project.references, referenced)
+	}
+
+	return project, nil
+}
+
+// projectConfigPath returns the absolute path of a tsconfig file, or of the
+// tsconfig.json in a directory
+func projectConfigPath(path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		absPath = filepath.Join(absPath, "tsconfig.json")
+		if _, err := os.Stat(absPath); err != nil {
+			return "", err
+		}
+	}
+
+	return absPath, nil
+}
+
+// readTSConfig reads a tsconfig file, which may contain comments and trailing commas
+func readTSConfig(path string) (*tsconfigFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config tsconfigFile
+	if err := json.Unmarshal(stripJSONC(data), &config); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &config, nil
+}
+
+// resolveTSConfig applies the extends chain of a config. Options set by a
+// config override those it extends; file lists are replaced, not merged.
+func resolveTSConfig(path string, depth int) (*tsconfigResolved, error) {
+	if depth > maxExtendsDepth {
+		return nil, fmt.Errorf("%s: extends chain is too long", path)
+	}
+
+	config, err := readTSConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(path)
+
+	resolved := &tsconfigResolved{}
+	for _, base := range config.extendsList() {
+		basePath, err := resolveExtends(dir, base)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		parent, err := resolveTSConfig(basePath, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		resolved.merge(parent)
+	}
+	resolved.configFiles = append(resolved.configFiles, path)
+
+	if config.Files != nil {
+		resolved.files, resolved.filesDir = config.Files, dir
+	}
+	if config.Include != nil {
+		resolved.include, resolved.includeDir = config.Include, dir
+	}
+	if config.Exclude != nil {
+		resolved.exclude, resolved.excludeDir = config.Exclude, dir
+	}
+
+	var baseURL, outDir string
+	if raw, ok := config.CompilerOptions["baseUrl"]; ok && json.Unmarshal(raw, &baseURL) == nil {
+		resolved.baseURL = filepath.Join(dir, baseURL)
+	}
+	if raw, ok := config.CompilerOptions["outDir"]; ok && json.Unmarshal(raw, &outDir) == nil {
+		resolved.outDir = filepath.Join(dir, outDir)
+	}
+	if raw, ok := config.CompilerOptions["paths"]; ok {
+		var paths map[string][]string
+		if err := json.Unmarshal(raw, &paths); err != nil {
+			return nil, fmt.Errorf("%s: invalid paths: %w", path, err)
+		}
+		resolved.paths, resolved.pathsDir = paths, dir
+	}
+
+	return resolved, nil
+}
+
+// merge applies a config on top of the receiver, as a later extends entry does
+func (r *tsconfigResolved) merge(other *tsconfigResolved) {
+	if other.files != nil {
+		r.files, r.filesDir = other.files, other.filesDir
+	}
+	if other.include != nil {
+		r.include, r.includeDir = other.include, other.includeDir
+	}
+	if other.exclude != nil {
+		r.exclude, r.excludeDir = other.exclude, other.excludeDir
+	}
+	if other.baseURL != "" {
+		r.baseURL = other.baseURL
+	}
+	if other.outDir != "" {
+		r.outDir = other.outDir
+	}
+	if other.paths != nil {
+		r.paths, r.pathsDir = other.paths, other.pathsDir
+	}
+	r.configFiles = append(r.configFiles, other.configFiles...)
+}
+
+// extendsList returns the extends option, which is a string or, since
+// TypeScript 5.0, a list applied in order
+func (c *tsconfigFile) extendsList() []string {
+	if len(c.Extends) == 0 {
+		return nil
+	}
+
+	var single string
+	if json.Unmarshal(c.Extends, &single) == nil {
+		return []string{single}
+	}
+
+	var list []string
+	json.Unmarshal(c.Extends, &list)
+	return list
+}
+
+// resolveExtends finds the config named by an extends entry: a path relative
+// to the config, or a package in a node_modules directory
+func resolveExtends(dir string, name string) (string, error) {
+	var candidates []string
+	if filepath.IsAbs(name) || strings.HasPrefix(name, "./") || strings.HasPrefix(name, "../") {
+		path := name
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, name)
+		}
+		candidates = []string{path, path + ".json"}
+	} else {
+		for current := dir; ; current = filepath.Dir(current) {
+			path := filepath.Join(current, "node_modules", name)
+			candidates = append(candidates, path, path+".json", filepath.Join(path, "tsconfig.json"))
+			if filepath.Dir(current) == current {
+				break
+			}
+		}
+	}
+
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("cannot find extended config %q", name)
+}
+
+// projectFiles lists the files of a resolved config the way tsc does: every
+// file in files, plus the TypeScript files matched by include and not by exclude
+func (r *tsconfigResolved) projectFiles() ([]string, error) {
+	fileSet := make(map[string]bool)
+
+	if r.files != nil {
+		for _, file := range *r.files {
+			path := file
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(r.filesDir, file)
+			}
+			if _, err := os.Stat(path); err != nil {
+				return nil, fmt.Errorf("file %s: %w", file, err)
+			}
+			fileSet[path] = true
+		}
+	}
+
+	// include defaults to everything, unless files is set
+	include, includeDir := r.include, r.includeDir
+	if include == nil && r.files == nil {
+		include, includeDir = &[]string{"**/*"}, filepath.Dir(r.configFiles[len(r.configFiles)-1])
+	}
+
+	var excludes []string
+	if r.exclude != nil {
+		for _, pattern := range *r.exclude {
+			excludes = append(excludes, projectPattern(r.excludeDir, pattern))
+		}
+	} else {
+		configDir := filepath.Dir(r.configFiles[len(r.configFiles)-1])
+		for _, dir := range defaultProjectExcludes {
+			excludes = append(excludes, projectPattern(configDir, dir))
+		}
+		if r.outDir != "" {
+			excludes = append(excludes, projectPattern(r.outDir, "."))
+		}
+	}
+
+	if include != nil {
+		for _, pattern := range *include {
+			matches, err := matchProjectPattern(projectPattern(includeDir, pattern), excludes)
+			if err != nil {
+				return nil, err
+			}
+			for _, match := range matches {
+				fileSet[match] = true
+			}
+		}
+	}
+
+	files := make([]string, 0, len(fileSet))
+	for file := range fileSet {
+		// Like tsc, a declaration file is dropped when its source is in the project
+		if base, ok := strings.CutSuffix(file, ".d.ts"); ok && (fileSet[base+".ts"] || fileSet[base+".tsx"]) {
+			continue
+		}
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	return files, nil
+}
+
+// projectPattern turns an include or exclude entry into an absolute glob. A
+// last segment without an extension or wildcard names a directory.
+func projectPattern(dir string, pattern string) string {
+	pattern = filepath.ToSlash(filepath.Clean(filepath.Join(dir, pattern)))
+	last := pattern[strings.LastIndex(pattern, "/")+1:]
+	if !strings.ContainsAny(last, ".*?") {
+		pattern += "/**/*"
+	}
+	return pattern
+}
+
+// matchProjectPattern returns the TypeScript files matching an absolute
+// include glob and none of the exclude globs. Like tsc, wildcards skip files
+// and directories whose names start with a dot.
+func matchProjectPattern(pattern string, excludes []string) ([]string, error) {
+	// Walk from the part of the pattern before its first wildcard
+	root := pattern
+	if index := strings.IndexAny(pattern, "*?["); index >= 0 {
+		root = filepath.Dir(pattern[:index+1])
+	}
+
+	var matches []string
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+
+		slashPath := filepath.ToSlash(path)
+		if path != root && strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		for _, exclude := range excludes {
+			if ok, _ := doublestar.Match(exclude, slashPath); ok {
+				if entry.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			// A directory excluded by a directory pattern
+			if ok, _ := doublestar.Match(strings.TrimSuffix(exclude, "/**/*"), slashPath); ok && entry.IsDir() {
+				return filepath.SkipDir
+			}
+		}
+
+		if entry.IsDir() || !isTypeScriptFile(path) {
+			return nil
+		}
+		if ok, _ := doublestar.Match(pattern, slashPath); ok {
+			matches = append(matches, path)
+		}
+		return nil
+	})
+
+	return matches, err
+}
+
+// allFiles returns the files of the project and every project it references
+func (p *tsProject) allFiles() []string {
+	fileSet := make(map[string]bool)
+	p.walk(func(project *tsProject) {
+		for _, file := range project.files {
+			fileSet[file] = true
+		}
+	})
+
+	files := make([]string, 0, len(fileSet))
+	for file := range fileSet {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// walk calls fn for the project and every project it references, once each
+func (p *tsProject) walk(fn func(*tsProject)) {
+	seen := make(map[*tsProject]bool)
+	var visit func(project *tsProject)
+	visit = func(project *tsProject) {
+		if seen[project] {
+			return
+		}
+		seen[project] = true
+		fn(project)
+		for _, reference := range project.references {
+			visit(reference)
+		}
+	}
+	visit(p)
+}
+
+// owner returns the project a file belongs to, falling back to the root project
+func (p *tsProject) owner(file string) *tsProject {
+	var owner *tsProject
+	p.walk(func(project *tsProject) {
+		if owner == nil && project.fileSet[file] {
+			owner = project
+		}
+	})
+	if owner == nil {
+		return p
+	}
+	return owner
+}
+
+// fingerprint hashes every config file read, since paths change how imports resolve
+func (p *tsProject) fingerprint() ([]byte, error) {
+	hash := sha256.New()
+	var err error
+	p.walk(func(project *tsProject) {
+		for _, path := range project.configFiles {
+			if err == nil {
+				err = hashFile(hash, path)
+			}
+		}
+	})
+	return hash.Sum(nil), err
+}
+
+// resolveNonRelative resolves a bare module specifier with the paths and
+// baseUrl of the project owning the importing file
+func (p *tsProject) resolveNonRelative(fromFile string, source string) (string, bool) {
+	project := p.owner(fromFile)
+
+	// The pattern with the longest prefix before its wildcard wins
+	bestPattern, bestPrefix, bestCapture := "", -1, ""
+	for pattern := range project.paths {
+		prefix, suffix, hasWildcard := strings.Cut(pattern, "*")
+		switch {
+		case !hasWildcard && pattern == source:
+			bestPattern, bestPrefix, bestCapture = pattern, len(pattern)+1, ""
+		case hasWildcard && len(prefix) > bestPrefix && len(source) >= len(prefix)+len(suffix) &&
+			strings.HasPrefix(source, prefix) && strings.HasSuffix(source, suffix):
+			bestPattern, bestPrefix, bestCapture = pattern, len(prefix), source[len(prefix):len(source)-len(suffix)]
+		}
+	}
+
+	if bestPrefix >= 0 {
+		for _, target := range project.paths[bestPattern] {
+			path := strings.Replace(target, "*", bestCapture, 1)
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(project.pathsBase, path)
+			}
+			if resolved, ok := resolveModulePath(path); ok {
+				return resolved, true
+			}
+		}
+	}
+
+	if project.baseURL != "" {
+		return resolveModulePath(filepath.Join(project.baseURL, source))
+	}
+	return "", false
+}
+
+// stripJSONC removes comments and trailing commas so JSON with comments, as
+// used by tsconfig files, can be decoded with encoding/json
+func stripJSONC(data []byte) []byte {
+	var out bytes.Buffer
+	inString := false
+
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+
+		if inString {
+			out.WriteByte(c)
+			if c == '\\' && i+1 < len(data) {
+				i++
+				out.WriteByte(data[i])
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+
+		switch {
+		case c == '"':
+			inString = true
+			out.WriteByte(c)
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			out.WriteByte('\n')
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			end := bytes.Index(data[i+2:], []byte("*/"))
+			if end < 0 {
+				i = len(data)
+			} else {
+				i += end + 3
+			}
+			out.WriteByte(' ')
+		case c == ',':
+			// Drop the comma when only whitespace and comments separate it from a closing bracket
+			if next := nextJSONCToken(data, i+1); next == '}' || next == ']' {
+				continue
+			}
+			out.WriteByte(c)
+		default:
+			out.WriteByte(c)
+		}
+	}
+
+	return out.Bytes()
+}
+
+// nextJSONCToken returns the next byte after start that is not whitespace or
+// part of a comment, or 0 at the end of the data
+func nextJSONCToken(data []byte, start int) byte {
+	for i := start; i < len(data); i++ {
+		switch {
+		case data[i] == ' ' || data[i] == '\t' || data[i] == '\r' || data[i] == '\n':
+		case data[i] == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+		case data[i] == '/' && i+1 < len(data) && data[i+1] == '*':
+			end := bytes.Index(data[i+2:], []byte("*/"))
+			if end < 0 {
+				return 0
+			}
+			i += end + 3
+		default:
+			return data[i]
+		}
+	}
+	return 0
+}
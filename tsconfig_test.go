@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeFiles creates files with the given content under dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+}
+
+func TestLoadProject(t *testing.T) {
+	tempDir := t.TempDir()
+	writeFiles(t, tempDir, map[string]string{
+		"node_modules/@company/tsconfig/base.json": `{
+			"compilerOptions": { "strict": true, "baseUrl": "." }
+		}`,
+		"tsconfig.base.json": `{
+			// Shared options
+			"extends": "@company/tsconfig/base.json",
+			"exclude": ["**/*.spec.ts"],
+			"compilerOptions": {
+				"baseUrl": ".",
+				"paths": { "@core/*": ["packages/core/src/*"], },
+			},
+		}`,
+		"tsconfig.json": `{
+			"extends": "./tsconfig.base",
+			"include": ["src"],
+			"files": ["scripts/build.ts"],
+			"references": [{ "path": "./packages/core" }]
+		}`,
+		"packages/core/tsconfig.json": `{
+			"extends": "../../tsconfig.base.json",
+			"include": ["src/**/*"],
+			"exclude": ["src/generated"]
+		}`,
+		"src/index.ts":                     "import { getContext } from '@core/ctx';\n",
+		"src/index.spec.ts":                "",
+		"src/view.tsx":                     "",
+		"src/types.d.ts":                   "",
+		"src/legacy.js":                    "",
+		"src/.hidden/skip.ts":              "",
+		"scripts/build.ts":                 "",
+		"scripts/other.ts":                 "",
+		"packages/core/src/ctx.ts":         "export function getContext() {}\n",
+		"packages/core/src/ctx.d.ts":       "",
+		"packages/core/src/generated/a.ts": "",
+		"node_modules/pkg/index.ts":        "",
+	})
+
+	project, err := loadProject(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to load project: %v", err)
+	}
+
+	abs := func(names ...string) []string {
+		var paths []string
+		for _, name := range names {
+			paths = append(paths, filepath.Join(tempDir, name))
+		}
+		return paths
+	}
+
+	if expected := abs("scripts/build.ts", "src/index.ts", "src/types.d.ts", "src/view.tsx"); !reflect.DeepEqual(project.files, expected) {
+		t.Errorf("Expected root files %v, got %v", expected, project.files)
+	}
+	if expected := abs("packages/core/src/ctx.ts"); len(project.references) != 1 || !reflect.DeepEqual(project.references[0].files, expected) {
+		t.Errorf("Expected one reference with files %v", expected)
+	}
+	if len(project.allFiles()) != 5 {
+		t.Errorf("Expected 5 files across projects, got %v", project.allFiles())
+	}
+
+	activeProject = project
+	defer func() { activeProject = nil }()
+
+	from := filepath.Join(tempDir, "src/index.ts")
+	if path, ok := resolveModule(from, "@core/ctx"); !ok || path != filepath.Join(tempDir, "packages/core/src/ctx.ts") {
+		t.Errorf("Expected @core/ctx to resolve through paths, got %q", path)
+	}
+	if path, ok := resolveModule(from, "scripts/build"); !ok || path != filepath.Join(tempDir, "scripts/build.ts") {
+		t.Errorf("Expected scripts/build to resolve through baseUrl, got %q", path)
+	}
+	if _, ok := resolveModule(from, "@missing/ctx"); ok {
+		t.Error("Expected an unknown package not to resolve")
+	}
+	if !sameModule(from, "../packages/core/src/ctx", "@core/ctx") {
+		t.Error("Expected a relative import and a path alias of the same file to match")
+	}
+}
+
+func TestLoadProjectErrors(t *testing.T) {
+	tempDir := t.TempDir()
+	writeFiles(t, tempDir, map[string]string{
+		"missing-extends/tsconfig.json": `{ "extends": "./nope.json" }`,
+		"missing-file/tsconfig.json":    `{ "files": ["nope.ts"] }`,
+		"invalid/tsconfig.json":         `{ "include": [ }`,
+		"cycle/tsconfig.json":           `{ "extends": "./tsconfig.json" }`,
+	})
+
+	for _, dir := range []string{"missing-extends", "missing-file", "invalid", "cycle", "no-such-dir"} {
+		if _, err := loadProject(filepath.Join(tempDir, dir)); err == nil {
+			t.Errorf("Expected an error loading %s", dir)
+		}
+	}
+}
+
+func TestStripJSONC(t *testing.T) {
+	input := `{
+		// line comment
+		"a": "http://example.com", /* block */
+		"b": [1, 2, /* trailing */ ],
+		"c": "quote \" // not a comment",
+	}`
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(stripJSONC([]byte(input)), &decoded); err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"a": "http://example.com",
+		"b": []interface{}{1.0, 2.0},
+		"c": `quote " // not a comment`,
+	}
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Errorf("Expected %v, got %v", expected, decoded)
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/typescript/typescript"
+)
+
+// exportUsage records which exports of the analyzed files other files import
+type exportUsage struct {
+	modules map[string]*moduleInfo     // By absolute path
+	used    map[string]map[string]bool // Local names by declaring file; "*" marks every export
+}
+
+// buildExportUsage parses every file and follows each of its imports, through
+// re-export chains, to the declaration it names. Imports of a file by itself
+// do not count.
+func buildExportUsage(files []string) (*exportUsage, error) {
+	usage := &exportUsage{
+		modules: make(map[string]*moduleInfo),
+		used:    make(map[string]map[string]bool),
+	}
+
+	parser := sitter.NewParser()
+	parser.SetLanguage(typescript.GetLanguage())
+
+	// Dynamic imports cannot be followed by name, so they use the whole module
+	dynamic := make(map[string][]string)
+
+	for _, file := range files {
+		absPath, err := filepath.Abs(file)
+		if err != nil {
+			return nil, err
+		}
+		content, err := os.ReadFile(absPath)
+		if err != nil {
+			return nil, err
+		}
+
+		rootNode := parser.Parse(nil, content).RootNode()
+		usage.modules[absPath] = parseModuleInfo(rootNode, content)
+		for _, imp := range findFileImports(rootNode, content) {
+			if imp.dynamic {
+				dynamic[absPath] = append(dynamic[absPath], imp.source)
+			}
+		}
+	}
+
+	for path, info := range usage.modules {
+		for _, binding := range info.imports {
+			if binding.Imported != "" {
+				usage.markImport(path, binding.Source, binding.Imported)
+			}
+		}
+		for _, source := range dynamic[path] {
+			usage.markImport(path, source, "*")
+		}
+	}
+
+	return usage, nil
+}
+
+// markImport marks the export a file imports from a module as used
+func (u *exportUsage) markImport(fromFile string, source string, name string) {
+	target, ok := resolveModule(fromFile, source)
+	if !ok || target == fromFile {
+		return
+	}
+
+	resolver := exportResolver{module: func(path string) *moduleInfo { return u.modules[path] }}
+	resolver.resolve(target, name, func(path string, local string) bool {
+		u.mark(path, local)
+		return false
+	})
+}
+
+// mark records a local name of a file as used
+func (u *exportUsage) mark(path string, local string) {
+	if u.used[path] == nil {
+		u.used[path] = make(map[string]bool)
+	}
+	u.used[path][local] = true
+}
+
+// isUsed reports whether another file imports a local name of a file
+func (u *exportUsage) isUsed(path string, local string) bool {
+	return u.used[path]["*"] || u.used[path][local]
+}
+
+// exportedDeclaration is an exported function or constant of a file
+type exportedDeclaration struct {
+	name string
+	kind string // "function" or "constant"
+	line uint32
+}
+
+// findExportedDeclarations returns the exported functions selected by the
+// exported function query, and the exported constants, in source order
+func findExportedDeclarations(rootNode *sitter.Node, content []byte) ([]exportedDeclaration, error) {
+	functions, err := selectFunctions(rootNode, "exported")
+	if err != nil {
+		return nil, err
+	}
+
+	isFunction := make(map[uint32]bool)
+	var declarations []exportedDeclaration
+	for _, funcNode := range functions {
+		isFunction[funcNode.StartByte()] = true
+		declarations = append(declarations, exportedDeclaration{
+			name: declarationName(funcNode, content),
+			kind: "function",
+			line: funcNode.StartPoint().Row + 1,
+		})
+	}
+
+	for i := 0; i < int(rootNode.NamedChildCount()); i++ {
+		statement := rootNode.NamedChild(i)
+		if statement.Type() != "export_statement" {
+			continue
+		}
+		declaration := statement.ChildByFieldName("declaration")
+		if declaration == nil || declaration.Type() != "lexical_declaration" || declaration.Child(0).Type() != "const" {
+			continue
+		}
+
+		for j := 0; j < int(declaration.NamedChildCount()); j++ {
+			declarator := declaration.NamedChild(j)
+			nameNode := declarator.ChildByFieldName("name")
+			if declarator.Type() != "variable_declarator" || nameNode == nil || nameNode.Type() != "identifier" {
+				continue
+			}
+			if value := declarator.ChildByFieldName("value"); value != nil && isFunction[value.StartByte()] {
+				continue
+			}
+			declarations = append(declarations, exportedDeclaration{
+				name: nodeText(nameNode, content),
+				kind: "constant",
+				line: declarator.StartPoint().Row + 1,
+			})
+		}
+	}
+
+	sort.SliceStable(declarations, func(i, j int) bool {
+		return declarations[i].line < declarations[j].line
+	})
+	return declarations, nil
+}
+
+// declarationName returns the local name of a function selected by the
+// exported function query
+func declarationName(funcNode *sitter.Node, content []byte) string {
+	if nameNode := funcNode.ChildByFieldName("name"); nameNode != nil {
+		return nodeText(nameNode, content)
+	}
+	if parent := funcNode.Parent(); parent != nil && parent.Type() == "variable_declarator" {
+		if nameNode := parent.ChildByFieldName("name"); nameNode != nil {
+			return nodeText(nameNode, content)
+		}
+	}
+	return anonymousDefault
+}
+
+// hasUnusedExportsRule reports whether any rule needs the export usage index
+func hasUnusedExportsRule(rules []*Rule) bool {
+	for _, rule := range rules {
+		if rule.Type == ruleTypeUnused {
+			return true
+		}
+	}
+	return false
+}
+
+// checkUnusedExports reports the exported functions and constants of a file
+// that no other file imports. Exports that are part of the package's public
+// API are used by other packages and are never reported.
+func (r *Rule) checkUnusedExports(rootNode *sitter.Node, content []byte, filename string) ([]Finding, error) {
+	if r.usage == nil {
+		return nil, nil
+	}
+
+	declarations, err := findExportedDeclarations(rootNode, content)
+	if err != nil {
+		return nil, fmt.Errorf("finding the exports of %s: %w", filename, err)
+	}
+
+	api := publicAPIFor(filename)
+
+	var findings []Finding
+	for _, declaration := range declarations {
+		if r.usage.isUsed(filename, declaration.name) {
+			continue
+		}
+		if api != nil && api.public[filename][declaration.name] {
+			logger.Debug("export is part of the public API", "file", filename, "name", declaration.name, "package", api.dir)
+			continue
+		}
+
+		name := declaration.name
+		if name == anonymousDefault {
+			name = "default"
+		}
+		findings = append(findings, Finding{
+			Line:    declaration.line,
+			Message: fmt.Sprintf("Exported %s %q is not imported by any other file", declaration.kind, name),
+		})
+	}
+
+	return findings, nil
+}
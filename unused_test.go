@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestUnusedExports(t *testing.T) {
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"package.json": `{ "name": "pkg", "main": "dist/index.js" }`,
+		"src/index.ts": "export { api } from './api';\n",
+		"src/api.ts":   "export function api() {}\nexport function helper() {}\n",
+		"src/lib.ts": `import { used, CONST } from './shared';
+import * as ns from './ns';
+import def from './def';
+import { viaBarrel } from './barrel';
+const lazy = import('./dyn');
+export let counter = 0;
+`,
+		"src/shared.ts": `export const used = () => 1;
+export const CONST = 1;
+export const UNUSED = 2, ALSO_UNUSED = 3;
+export function orphan() {}
+`,
+		"src/ns.ts":     "export function a() {}\n",
+		"src/def.ts":    "export default function def() {}\nexport function other() {}\n",
+		"src/dyn.ts":    "export function lazy() {}\n",
+		"src/barrel.ts": "export { viaBarrel } from './deep';\n",
+		"src/deep.ts":   "export function viaBarrel() {}\n",
+		"src/self.ts":   "import { selfish } from './self';\nexport function selfish() {}\n",
+	}
+	writeFiles(t, tempDir, files)
+
+	var paths []string
+	for name := range files {
+		if isTypeScriptFile(name) {
+			paths = append(paths, filepath.Join(tempDir, name))
+		}
+	}
+	usage, err := buildExportUsage(paths)
+	if err != nil {
+		t.Fatalf("Failed to collect imports: %v", err)
+	}
+
+	rule := &Rule{Type: ruleTypeUnused, usage: usage}
+	if err := rule.validate(); err != nil {
+		t.Fatalf("Expected an unused-exports rule to be valid: %v", err)
+	}
+
+	expected := map[string][]Finding{
+		"src/api.ts": {{Line: 2, Message: `Exported function "helper" is not imported by any other file`, Severity: severityError}},
+		"src/shared.ts": {
+			{Line: 3, Message: `Exported constant "UNUSED" is not imported by any other file`, Severity: severityError},
+			{Line: 3, Message: `Exported constant "ALSO_UNUSED" is not imported by any other file`, Severity: severityError},
+			{Line: 4, Message: `Exported function "orphan" is not imported by any other file`, Severity: severityError},
+		},
+		"src/def.ts":  {{Line: 2, Message: `Exported function "other" is not imported by any other file`, Severity: severityError}},
+		"src/self.ts": {{Line: 2, Message: `Exported function "selfish" is not imported by any other file`, Severity: severityError}},
+	}
+
+	for _, path := range paths {
+		name, _ := filepath.Rel(tempDir, path)
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", name, err)
+		}
+		result, err := analyzeContent(content, []*Rule{rule}, path)
+		if err != nil {
+			t.Fatalf("Failed to analyze %s: %v", name, err)
+		}
+		findings := result.Findings
+		if !reflect.DeepEqual(findings, expected[name]) {
+			t.Errorf("Expected findings %v for %s, got %v", expected[name], name, findings)
+		}
+	}
+}
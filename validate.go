@@ -0,0 +1,217 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Rules kept as YAML nodes until it is known what they reconfigure
+var yamlNodeType = reflect.TypeOf(yaml.Node{})
+
+// Farthest a misspelled key can be from a known one to be suggested
+const maxKeySuggestionDistance = 2
+
+// runValidateConfig implements the validate-config subcommand and returns the exit code
+func runValidateConfig(args []string) int {
+	flags := flag.NewFlagSet("validate-config", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: ts-analyzer validate-config [file ...]\n\nChecks configuration files strictly, %s by default.\n", packageConfigName)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitUsageError
+	}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{packageConfigName}
+	}
+
+	code := exitClean
+	for _, path := range paths {
+		if !validateConfigFile(os.Stdout, path) {
+			code = exitUsageError
+		}
+	}
+	return code
+}
+
+// validateConfigFile checks a configuration file and the files it extends,
+// and applies its overrides to its rules, as for a file every override
+// matches, so their rules are checked too. It reports whether it is valid.
+func validateConfigFile(w io.Writer, path string) bool {
+	config, entries, err := loadInheritedConfig(path)
+	if err == nil {
+		for _, override := range config.Overrides {
+			if _, err = override.apply(entries); err != nil {
+				break
+			}
+		}
+	}
+
+	if err != nil {
+		fmt.Fprintf(w, "%s: invalid\n  %s\n", path, strings.ReplaceAll(err.Error(), "\n", "\n  "))
+		return false
+	}
+	rules := 0
+	for _, entry := range entries {
+		if !entry.disabled {
+			rules++
+		}
+	}
+	fmt.Fprintf(w, "%s: valid, %d rule(s), %d override(s)\n", path, rules, len(config.Overrides))
+	return true
+}
+
+// loadInheritedConfig reads a configuration file and returns it with the
+// rules it gives the files under it. A .ts-analyzer.yaml applies on top of
+// those of the directories above it, up to one with root: true, like in a
+// run whose -config is the outermost of them.
+func loadInheritedConfig(path string) (*Config, []ruleEntry, error) {
+	config, err := readConfig(path, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	chain := []*Config{config}
+	if filepath.Base(path) == packageConfigName {
+		dir, err := filepath.Abs(filepath.Dir(path))
+		if err != nil {
+			return nil, nil, err
+		}
+		for !chain[0].Root && filepath.Dir(dir) != dir {
+			dir = filepath.Dir(dir)
+			parent, err := loadDirectoryConfig(filepath.Join(dir, packageConfigName))
+			if err != nil {
+				return nil, nil, err
+			}
+			if parent != nil {
+				chain = append([]*Config{parent}, chain...)
+			}
+		}
+	}
+
+	var entries []ruleEntry
+	for _, change := range chain {
+		if entries, err = change.apply(entries); err != nil {
+			return nil, nil, err
+		}
+	}
+	return config, entries, nil
+}
+
+// decodeStrict decodes YAML into out after checking that every key is known
+func decodeStrict(data []byte, out any) error {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return err
+	}
+	if len(document.Content) == 0 {
+		return nil
+	}
+
+	if errs := checkKeys(&document, reflect.TypeOf(out)); len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	return document.Decode(out)
+}
+
+// checkKeys reports the keys of a YAML mapping, and of the mappings inside
+// it, that are not decoded into any field of the target type. Values of the
+// wrong kind are left for decoding to report.
+func checkKeys(node *yaml.Node, target reflect.Type) []error {
+	for target.Kind() == reflect.Pointer {
+		target = target.Elem()
+	}
+	if target == yamlNodeType {
+		target = reflect.TypeOf(Rule{})
+	}
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		return checkKeys(node.Content[0], target)
+	case yaml.AliasNode:
+		return checkKeys(node.Alias, target)
+	case yaml.SequenceNode:
+		if target.Kind() != reflect.Slice {
+			return nil
+		}
+		var errs []error
+		for _, item := range node.Content {
+			errs = append(errs, checkKeys(item, target.Elem())...)
+		}
+		return errs
+	case yaml.MappingNode:
+		if target.Kind() != reflect.Struct {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	fields := yamlFields(target)
+	var errs []error
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		index, ok := fields[key.Value]
+		if !ok {
+			err := fmt.Errorf("line %d: unknown key %q", key.Line, key.Value)
+			if suggestion := closestKey(key.Value, fields); suggestion != "" {
+				err = fmt.Errorf("%w, did you mean %q?", err, suggestion)
+			}
+			errs = append(errs, err)
+			continue
+		}
+		errs = append(errs, checkKeys(node.Content[i+1], target.FieldByIndex(index).Type)...)
+	}
+	return errs
+}
+
+// closestKey returns the known key closest to a misspelled one, or "" when
+// none is close
+func closestKey(key string, fields map[string][]int) string {
+	var names []string
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	closest, best := "", maxKeySuggestionDistance+1
+	for _, name := range names {
+		if distance := editDistance(key, name); distance < best {
+			closest, best = name, distance
+		}
+	}
+	return closest
+}
+
+// editDistance returns the Levenshtein distance between two strings
+func editDistance(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
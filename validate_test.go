@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateConfigFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected []string
+	}{
+		{
+			name:     "valid",
+			content:  "extends: ts-analyzer:no-console\nrules:\n  - code-block: a\noverrides:\n  - files: src\n    disable: [no-console]\n",
+			expected: []string{"valid, 2 rule(s), 1 override(s)"},
+		},
+		{
+			name: "unknown keys",
+			content: `rules:
+  - code-block: a
+    fn-type: exported
+    before: {txt: a}
+    import: {module: m, nam: n}
+overrides:
+  - files: src
+    rule: []
+`,
+			expected: []string{
+				`line 3: unknown key "fn-type", did you mean "fn-types"?`,
+				`line 4: unknown key "txt", did you mean "text"?`,
+				`line 5: unknown key "nam", did you mean "name"?`,
+				`line 8: unknown key "rule", did you mean "rules"?`,
+			},
+		},
+		{
+			name:     "unknown key without suggestion",
+			content:  "checks: []\n",
+			expected: []string{`line 1: unknown key "checks"`},
+		},
+		{
+			name:     "invalid regex",
+			content:  "rules:\n  - code-block: '(a'\n    regex: true\n",
+			expected: []string{"rule 1: invalid code-block regex"},
+		},
+		{
+			name:     "invalid query",
+			content:  "rules:\n  - type: order\n    before: {query: '(nonsense'}\n    after: b\n",
+			expected: []string{"rule 1: invalid before query"},
+		},
+		{
+			name:     "unknown function type",
+			content:  "rules:\n  - code-block: a\n    fn-types: exported, interal\n",
+			expected: []string{`unknown function types "interal"`},
+		},
+		{
+			name:     "invalid override rule",
+			content:  "rules:\n  - name: a\n    code-block: a\noverrides:\n  - files: src\n    rules:\n      - name: a\n        fn-types: all\n",
+			expected: []string{"override 1: rule 1: invalid function types"},
+		},
+	}
+
+	for _, test := range tests {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		writeFiles(t, filepath.Dir(path), map[string]string{"config.yaml": test.content})
+
+		var output bytes.Buffer
+		valid := validateConfigFile(&output, path)
+		if valid != (test.name == "valid") {
+			t.Errorf("%s: expected valid=%v, got output:\n%s", test.name, !valid, output.String())
+		}
+		for _, expected := range test.expected {
+			if !strings.Contains(output.String(), expected) {
+				t.Errorf("%s: expected output containing %q, got:\n%s", test.name, expected, output.String())
+			}
+		}
+	}
+}
+
+func TestValidateDirectoryConfig(t *testing.T) {
+	tempDir := t.TempDir()
+	writeFiles(t, tempDir, map[string]string{
+		packageConfigName:                 "rules:\n  - name: context\n    code-block: getContext()\n",
+		"src/" + packageConfigName:        "rules:\n  - name: context\n    severity: warning\n",
+		"src/legacy/" + packageConfigName: "disable: [ctx]\nroot: true\n",
+		"src/broken/" + packageConfigName: "rules:\n  - name: missing\n    severity: warning\n",
+	})
+
+	tests := map[string]string{
+		"src":        "valid, 1 rule(s), 0 override(s)",
+		"src/legacy": "valid, 0 rule(s), 0 override(s)",
+		"src/broken": "rule 1: code-block is required",
+	}
+	for dir, expected := range tests {
+		var output bytes.Buffer
+		valid := validateConfigFile(&output, filepath.Join(tempDir, dir, packageConfigName))
+		if valid != strings.Contains(expected, "valid,") || !strings.Contains(output.String(), expected) {
+			t.Errorf("%s: expected output containing %q, got:\n%s", dir, expected, output.String())
+		}
+	}
+}
+
+func TestDirectoryConfigUnknownKeys(t *testing.T) {
+	tempDir := t.TempDir()
+	writeFiles(t, tempDir, map[string]string{packageConfigName: "root: true\ndisabled: [a]\n"})
+
+	_, err := loadDirectoryConfig(filepath.Join(tempDir, packageConfigName))
+	if err == nil || !strings.Contains(err.Error(), `unknown key "disabled", did you mean "disable"?`) {
+		t.Errorf("Expected an unknown key error, got %v", err)
+	}
+}
+
+func TestUnknownFunctionTypes(t *testing.T) {
+	if unknown := unknownFunctionTypes("exported, internal,public-api"); len(unknown) != 0 {
+		t.Errorf("Expected no unknown types, got %v", unknown)
+	}
+	if unknown := unknownFunctionTypes("exported,interal,"); strings.Join(unknown, " ") != `"interal" ""` {
+		t.Errorf("Expected the misspelled and empty types, got %v", unknown)
+	}
+}
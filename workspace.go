@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"gopkg.in/yaml.v3"
+)
+
+// Configuration file a subdirectory, such as a workspace package, can use to
+// change the rules of the files under it
+const packageConfigName = ".ts-analyzer.yaml"
+
+// Name of the group for files that belong to no workspace package
+const rootPackageName = "(root)"
+
+// workspacePackage is a package of a pnpm or npm workspace
+type workspacePackage struct {
+	Name string
+	Dir  string // Absolute
+
+	// Results, filled in while checking
+	files           int
+	issues          int
+	severities      severityCounts
+	filesWithIssues []string
+}
+
+// pnpmWorkspace is the content of pnpm-workspace.yaml
+type pnpmWorkspace struct {
+	Packages []string `yaml:"packages"`
+}
+
+// packageJSON is the part of package.json the analyzer reads
+type packageJSON struct {
+	Name       string          `json:"name"`
+	Workspaces json.RawMessage `json:"workspaces"`
+}
+
+// findWorkspacePackages discovers the packages of the workspace rooted at
+// root from pnpm-workspace.yaml or, failing that, the workspaces field of
+// package.json. Patterns starting with ! exclude packages.
+func findWorkspacePackages(root string) ([]*workspacePackage, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	patterns, err := workspacePatterns(absRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	var includes, excludes []string
+	for _, pattern := range patterns {
+		if negated, ok := strings.CutPrefix(pattern, "!"); ok {
+			excludes = append(excludes, cleanWorkspacePattern(negated))
+		} else {
+			includes = append(includes, cleanWorkspacePattern(pattern))
+		}
+	}
+
+	dirs := make(map[string]bool)
+	for _, pattern := range includes {
+		matches, err := doublestar.Glob(os.DirFS(absRoot), pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid workspace pattern %q: %w", pattern, err)
+		}
+	matchLoop:
+		for _, match := range matches {
+			if strings.Contains(match, "node_modules") {
+				continue
+			}
+			for _, exclude := range excludes {
+				if ok, _ := doublestar.Match(exclude, match); ok {
+					continue matchLoop
+				}
+			}
+			if _, err := os.Stat(filepath.Join(absRoot, match, "package.json")); err == nil {
+				dirs[match] = true
+			}
+		}
+	}
+
+	var packages []*workspacePackage
+	for dir := range dirs {
+		absDir := filepath.Join(absRoot, filepath.FromSlash(dir))
+		name := dir
+		if manifest, err := readPackageJSON(absDir); err == nil && manifest.Name != "" {
+			name = manifest.Name
+		}
+		packages = append(packages, &workspacePackage{Name: name, Dir: absDir})
+	}
+
+	sort.Slice(packages, func(i, j int) bool {
+		return packages[i].Name < packages[j].Name
+	})
+
+	return packages, nil
+}
+
+// workspacePatterns returns the package patterns of a workspace
+func workspacePatterns(root string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(root, "pnpm-workspace.yaml"))
+	if err == nil {
+		var workspace pnpmWorkspace
+		if err := yaml.Unmarshal(data, &workspace); err != nil {
+			return nil, fmt.Errorf("parsing pnpm-workspace.yaml: %w", err)
+		}
+		return workspace.Packages, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	manifest, err := readPackageJSON(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no pnpm-workspace.yaml or package.json in %s", root)
+		}
+		return nil, err
+	}
+	if len(manifest.Workspaces) == 0 {
+		return nil, fmt.Errorf("package.json in %s has no workspaces field", root)
+	}
+
+	// workspaces is a list of patterns, or an object with a packages list (Yarn)
+	var patterns []string
+	if err := json.Unmarshal(manifest.Workspaces, &patterns); err == nil {
+		return patterns, nil
+	}
+	var object struct {
+		Packages []string `json:"packages"`
+	}
+	if err := json.Unmarshal(manifest.Workspaces, &object); err != nil {
+		return nil, fmt.Errorf("invalid workspaces field in package.json: %w", err)
+	}
+	return object.Packages, nil
+}
+
+// readPackageJSON reads the package.json in a directory
+func readPackageJSON(dir string) (*packageJSON, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest packageJSON
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", filepath.Join(dir, "package.json"), err)
+	}
+	return &manifest, nil
+}
+
+// cleanWorkspacePattern makes a workspace pattern usable with io/fs globbing
+func cleanWorkspacePattern(pattern string) string {
+	pattern = strings.TrimPrefix(pattern, "./")
+	return strings.TrimSuffix(pattern, "/")
+}
+
+// packageFor returns the package containing a file: the one with the
+// longest directory that is a prefix of the path
+func packageFor(packages []*workspacePackage, path string) *workspacePackage {
+	var owner *workspacePackage
+	for _, pkg := range packages {
+		if path == pkg.Dir || strings.HasPrefix(path, pkg.Dir+string(filepath.Separator)) {
+			if owner == nil || len(pkg.Dir) > len(owner.Dir) {
+				owner = pkg
+			}
+		}
+	}
+	return owner
+}
+
+// printWorkspaceSummary prints the files with issues grouped by package
+func printWorkspaceSummary(packages []*workspacePackage, invalidFiles map[string]int, rules []*Rule) {
+	fmt.Println("\nSummary of packages with issues:")
+
+	label := issueLabel(rules)
+	for _, pkg := range packages {
+		if len(pkg.filesWithIssues) == 0 {
+			continue
+		}
+
+		fmt.Printf("\n%s (%d of %d file(s) with issues, %s)\n", pkg.Name, len(pkg.filesWithIssues), pkg.files, pkg.severities)
+		sort.Strings(pkg.filesWithIssues)
+		for _, path := range pkg.filesWithIssues {
+			fmt.Printf("  %s: %d %s\n", path, invalidFiles[path], label)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestFindWorkspacePackages(t *testing.T) {
+	testCases := []struct {
+		name     string
+		files    map[string]string
+		expected []string
+	}{
+		{
+			name: "pnpm",
+			files: map[string]string{
+				"pnpm-workspace.yaml":              "packages:\n  - 'packages/*'\n  - 'apps/**'\n  - '!**/fixtures/**'\n",
+				"package.json":                     `{"name": "root", "workspaces": ["ignored/*"]}`,
+				"packages/a/package.json":          `{"name": "@acme/a"}`,
+				"packages/b/package.json":          `{}`,
+				"packages/no-manifest/index.ts":    "",
+				"apps/web/package.json":            `{"name": "web"}`,
+				"apps/web/fixtures/x/package.json": `{"name": "fixture"}`,
+			},
+			expected: []string{"@acme/a", "packages/b", "web"},
+		},
+		{
+			name: "npm",
+			files: map[string]string{
+				"package.json":            `{"workspaces": ["./packages/*/"]}`,
+				"packages/a/package.json": `{"name": "a"}`,
+			},
+			expected: []string{"a"},
+		},
+		{
+			name: "yarn object",
+			files: map[string]string{
+				"package.json":                            `{"workspaces": {"packages": ["libs/*"]}}`,
+				"libs/core/package.json":                  `{"name": "core"}`,
+				"libs/core/node_modules/dep/package.json": `{"name": "dep"}`,
+			},
+			expected: []string{"core"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			writeFiles(t, tempDir, tc.files)
+
+			packages, err := findWorkspacePackages(tempDir)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			var names []string
+			for _, pkg := range packages {
+				names = append(names, pkg.Name)
+			}
+			if len(names) != len(tc.expected) {
+				t.Fatalf("Expected packages %v, got %v", tc.expected, names)
+			}
+			for i := range names {
+				if names[i] != tc.expected[i] {
+					t.Errorf("Expected packages %v, got %v", tc.expected, names)
+					break
+				}
+			}
+		})
+	}
+
+	if _, err := findWorkspacePackages(t.TempDir()); err == nil {
+		t.Error("Expected an error for a directory without a workspace")
+	}
+}
+
+func TestWorkspacePackageRules(t *testing.T) {
+	tempDir := t.TempDir()
+	writeFiles(t, tempDir, map[string]string{
+		"pnpm-workspace.yaml":               "packages: ['packages/*']\n",
+		"packages/api/package.json":         `{"name": "api"}`,
+		"packages/api/" + packageConfigName: "rules:\n  - code-block: authorize(\n",
+		"packages/api/nested/package.json":  `{"name": "nested"}`,
+		"packages/web/package.json":         `{"name": "web"}`,
+	})
+
+	packages, err := findWorkspacePackages(tempDir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	api := packageFor(packages, filepath.Join(tempDir, "packages/api/src/index.ts"))
+	if api == nil || api.Name != "api" {
+		t.Fatalf("Expected the file to belong to api, got %v", api)
+	}
+	web := packageFor(packages, filepath.Join(tempDir, "packages/web/index.ts"))
+	if web == nil || web.Name != "web" {
+		t.Fatalf("Expected the file to belong to web, got %v", web)
+	}
+
+	// A package's config cascades onto the run's rules
+	base := []*Rule{{Name: "context", CodeBlock: "getContext()"}}
+	resolver := newRuleResolver(tempDir, nil)
+	set, err := resolver.rulesFor(filepath.Join(tempDir, "packages/api/src/index.ts"), base, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if set == nil || len(set.rules) != 2 || set.rules[0].CodeBlock != "getContext()" || set.rules[1].CodeBlock != "authorize(" {
+		t.Errorf("Expected api to add its rules to the run's rules, got %v", set)
+	}
+
+	set, err = resolver.rulesFor(filepath.Join(tempDir, "packages/web/index.ts"), base, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if set != nil {
+		t.Errorf("Expected web to use the run's rules, got %v", set.rules)
+	}
+
+	if pkg := packageFor(packages, filepath.Join(tempDir, "packages/webapp/index.ts")); pkg != nil {
+		t.Errorf("Expected no package for a sibling directory with a common prefix, got %s", pkg.Name)
+	}
+}